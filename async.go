@@ -17,6 +17,8 @@ package healthcheck
 import (
 	"context"
 	"errors"
+	"log/slog"
+	"sync"
 	"time"
 )
 
@@ -24,14 +26,61 @@ import (
 // yet returned.
 var ErrNoData = errors.New("no data yet")
 
+// WrapperOption configures optional behavior of the checks returned by Async,
+// AsyncWithContext and Timeout.
+type WrapperOption func(*wrapperOptions)
+
+type wrapperOptions struct {
+	notify func(err error)
+	log    func(err error, duration time.Duration)
+}
+
+// LogTo logs the failures and status changes of a check returned by Async,
+// AsyncWithContext or Timeout to logger, as the check with the provided name.
+// Failures are logged at most once per minute.
+func LogTo(logger *slog.Logger, name string) WrapperOption {
+	return func(o *wrapperOptions) {
+		results := newResultLogger(logger, 0)
+		var mutex sync.Mutex
+		var status Status
+		o.log = func(err error, duration time.Duration) {
+			results.log("", name, err, duration)
+
+			result := checkResult{name: name}
+			result.setError(err)
+			mutex.Lock()
+			old := status
+			status = result.status
+			mutex.Unlock()
+			if old != result.status {
+				logTransition(logger, Event{Name: name, Old: old, New: result.status, Err: err, Time: time.Now()})
+			}
+		}
+	}
+}
+
+// NotifyHandler reports every result of an asynchronous check to handler as
+// soon as it is computed, so OnStatusChange listeners don't have to wait for
+// the next probe to learn about status changes. The kind and name must match
+// the ones the check is added to handler with.
+func NotifyHandler(handler Handler, kind Kind, name string) WrapperOption {
+	return func(o *wrapperOptions) {
+		if notifier, ok := handler.(statusNotifier); ok {
+			o.notify = func(err error) {
+				notifier.notify(kind, name, err)
+			}
+		}
+	}
+}
+
 // Async converts a Check into an asynchronous check that runs in a background
 // goroutine at a fixed interval. The check is called at a fixed rate, not with
 // a fixed delay between invocations. If your check takes longer than the
 // interval to execute, the next execution will happen immediately.
 //
 // Note: if you need to clean up the background goroutine, use AsyncWithContext().
-func Async(check Check, interval time.Duration) Check {
-	return AsyncWithContext(context.Background(), check, interval)
+func Async(check Check, interval time.Duration, opts ...WrapperOption) Check {
+	return AsyncWithContext(context.Background(), check, interval, opts...)
 }
 
 // AsyncWithContext converts a Check into an asynchronous check that runs in a
@@ -40,7 +89,12 @@ func Async(check Check, interval time.Duration) Check {
 // than the interval to execute, the next execution will happen immediately.
 //
 // Note: if you don't need to cancel execution (because this runs forever), use Async()
-func AsyncWithContext(ctx context.Context, check Check, interval time.Duration) Check {
+func AsyncWithContext(ctx context.Context, check Check, interval time.Duration, opts ...WrapperOption) Check {
+	var o wrapperOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	// create a chan that will buffer the most recent check result
 	result := make(chan error, 1)
 
@@ -52,9 +106,16 @@ func AsyncWithContext(ctx context.Context, check Check, interval time.Duration)
 	// make a wrapper that runs the check, and swaps out the current head of
 	// the channel with the latest result
 	update := func() {
-		err := check()
+		start := time.Now()
+		err := callSimpleCheck(check)
 		<-result
 		result <- err
+		if o.notify != nil {
+			o.notify(err)
+		}
+		if o.log != nil {
+			o.log(err, time.Since(start))
+		}
 	}
 
 	// spawn a background goroutine to run the check
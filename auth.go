@@ -0,0 +1,78 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+)
+
+// Authorizer decides whether a request may see the detailed check results.
+// Requests that aren't authorized still get the status code, but no details.
+type Authorizer func(r *http.Request) bool
+
+// Redactor rewrites the error message of the named check before it is
+// returned, for example to strip credentials, hostnames or IP addresses.
+type Redactor func(name string, message string) string
+
+// BearerToken returns an Authorizer that accepts requests with an
+// "Authorization: Bearer <token>" header.
+func BearerToken(token string) Authorizer {
+	expected := []byte("Bearer " + token)
+	return func(r *http.Request) bool {
+		return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) == 1
+	}
+}
+
+// ClientCertSubject returns an Authorizer that accepts requests with a
+// verified TLS client certificate whose subject common name is one of the
+// provided names. The server must be configured to verify client
+// certificates, for example with tls.VerifyClientCertIfGiven.
+func ClientCertSubject(names ...string) Authorizer {
+	return func(r *http.Request) bool {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			return false
+		}
+		subject := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		for _, name := range names {
+			if subject == name {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// authorized returns whether r may see the detailed check results.
+func (s *basicHandler) authorized(r *http.Request) bool {
+	return s.authorizer == nil || s.authorizer(r)
+}
+
+// redact returns a copy of results whose errors have been rewritten by the
+// redactor, if there is one.
+func (s *basicHandler) redact(results []checkResult) []checkResult {
+	if s.redactor == nil {
+		return results
+	}
+	redacted := make([]checkResult, len(results))
+	for i, result := range results {
+		if result.err != nil {
+			result.err = errors.New(s.redactor(result.name, result.err.Error()))
+		}
+		redacted[i] = result
+	}
+	return redacted
+}
@@ -0,0 +1,91 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Cache wraps a Check so that its result is reused for ttl after it returns,
+// and so that concurrent calls share a single execution. Unlike Async, the
+// check still only runs when it is called.
+func Cache(check Check, ttl time.Duration) Check {
+	return BackgroundCheck(CacheContext(ContextCheck(check), ttl))
+}
+
+// CacheContext wraps a CheckContext so that its result is reused for ttl after
+// it returns, and so that concurrent calls share a single execution. The
+// shared execution isn't tied to any caller, so it runs with a background
+// context; a caller whose context is done returns early with its error while
+// the execution carries on for the others.
+func CacheContext(check CheckContext, ttl time.Duration) CheckContext {
+	c := &cachedCheck{check: check, ttl: ttl}
+	return c.run
+}
+
+// cachedCheck is the state of a CacheContext-wrapped check.
+type cachedCheck struct {
+	check CheckContext
+	ttl   time.Duration
+
+	mutex   sync.Mutex
+	err     error
+	expires time.Time
+	call    *cachedCall
+}
+
+// cachedCall is an in-flight execution of a cached check.
+type cachedCall struct {
+	done chan struct{}
+	err  error
+}
+
+func (c *cachedCheck) run(ctx context.Context) error {
+	c.mutex.Lock()
+	if time.Now().Before(c.expires) {
+		defer c.mutex.Unlock()
+		return c.err
+	}
+	call := c.call
+	if call == nil {
+		call = &cachedCall{done: make(chan struct{})}
+		c.call = call
+		go c.execute(call)
+	}
+	c.mutex.Unlock()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// execute runs the check and caches its result. It runs on its own
+// goroutine, so it recovers from panics itself.
+func (c *cachedCheck) execute(call *cachedCall) {
+	call.err = callCheck(context.Background(), c.check)
+
+	c.mutex.Lock()
+	c.err = call.err
+	c.expires = time.Now().Add(c.ttl)
+	c.call = nil
+	c.mutex.Unlock()
+
+	close(call.done)
+}
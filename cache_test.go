@@ -0,0 +1,79 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	var calls int32
+	failing := errors.New("failing")
+	check := Cache(func() error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return failing
+		}
+		return nil
+	}, 20*time.Millisecond)
+
+	// failures are cached too
+	assert.Equal(t, failing, check())
+	assert.Equal(t, failing, check())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, check())
+	assert.NoError(t, check())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestCacheCollapsesConcurrentCalls(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	check := CacheContext(func(context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return nil
+	}, time.Minute)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = check(context.Background())
+		}(i)
+	}
+
+	// a caller that goes away doesn't wait for the shared execution
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, check(ctx))
+
+	close(release)
+	wg.Wait()
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,71 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+// CheckOption configures optional behavior of a single check when it is added
+// to a Handler.
+type CheckOption func(*registeredCheck)
+
+// WithTags tags a check with one or more groups (for example "storage" or
+// "critical"). A request with ?tag=storage only evaluates the checks tagged
+// "storage", and the full results list the tags of every check.
+func WithTags(tags ...string) CheckOption {
+	return func(c *registeredCheck) {
+		c.tags = append(c.tags, tags...)
+	}
+}
+
+// WithThresholds requires failure consecutive failures before a check is
+// reported as failed, and success consecutive successes before it is reported
+// as ok again, like the failureThreshold and successThreshold of Kubernetes
+// probes. This keeps single transient failures from failing the endpoints.
+// Thresholds < 1 count as 1.
+//
+// Like the kubelet, which tracks every probe separately, every kind of
+// endpoint keeps its own streaks: a liveness check that /ready runs too is
+// counted separately on /live and on /ready. Every request that runs the
+// check advances the streaks of its endpoint, including requests for a single
+// check, except requests for the full results, which only report them.
+func WithThresholds(failure, success int) CheckOption {
+	if failure < 1 {
+		failure = 1
+	}
+	if success < 1 {
+		success = 1
+	}
+	return func(c *registeredCheck) {
+		c.thresholds = &thresholds{failureThreshold: failure, successThreshold: success, streaks: make(map[Kind]*streak)}
+	}
+}
+
+// DependsOn declares the checks, by name, that a check depends on. When they
+// are evaluated together, the check only runs after its dependencies have
+// returned, and is reported with StatusSkipped if any of them failed. This
+// keeps a single upstream failure from making every downstream check wait
+// out its own timeout. Dependencies that aren't evaluated along with the
+// check are ignored.
+func DependsOn(names ...string) CheckOption {
+	return func(c *registeredCheck) {
+		c.dependencies = append(c.dependencies, names...)
+	}
+}
+
+// WithSeverity sets the severity of a check. Failures of SeverityWarning
+// checks are reported, but don't fail the endpoints the check belongs to.
+func WithSeverity(severity Severity) CheckOption {
+	return func(c *registeredCheck) {
+		c.severity = severity
+	}
+}
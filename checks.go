@@ -27,8 +27,15 @@ import (
 // TCPDialCheck returns a Check that checks TCP connectivity to the provided
 // endpoint.
 func TCPDialCheck(addr string, timeout time.Duration) Check {
-	return func() error {
-		conn, err := net.DialTimeout("tcp", addr, timeout)
+	return BackgroundCheck(TCPDialCheckContext(addr, timeout))
+}
+
+// TCPDialCheckContext is like TCPDialCheck, but returns a CheckContext that
+// gives up on dialing once the context is done.
+func TCPDialCheckContext(addr string, timeout time.Duration) CheckContext {
+	dialer := net.Dialer{Timeout: timeout}
+	return func(ctx context.Context) error {
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
 		if err != nil {
 			return err
 		}
@@ -40,6 +47,13 @@ func TCPDialCheck(addr string, timeout time.Duration) Check {
 // specified URL. The check fails if the response times out or returns a non-200
 // status code.
 func HTTPGetCheck(url string, timeout time.Duration) Check {
+	return BackgroundCheck(HTTPGetCheckContext(url, timeout))
+}
+
+// HTTPGetCheckContext is like HTTPGetCheck, but returns a CheckContext that
+// cancels the request once the context is done. When the handler traces its
+// requests with WithTracer, the trace context is propagated to the upstream.
+func HTTPGetCheckContext(url string, timeout time.Duration) CheckContext {
 	client := http.Client{
 		Timeout: timeout,
 		// never follow redirects
@@ -47,8 +61,13 @@ func HTTPGetCheck(url string, timeout time.Duration) Check {
 			return http.ErrUseLastResponse
 		},
 	}
-	return func() error {
-		resp, err := client.Get(url)
+	return func(ctx context.Context) error {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		injectTrace(ctx, req.Header)
+		resp, err := client.Do(req.WithContext(ctx))
 		if err != nil {
 			return err
 		}
@@ -63,8 +82,14 @@ func HTTPGetCheck(url string, timeout time.Duration) Check {
 // DatabasePingCheck returns a Check that validates connectivity to a
 // database/sql.DB using Ping().
 func DatabasePingCheck(database *sql.DB, timeout time.Duration) Check {
-	return func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	return BackgroundCheck(DatabasePingCheckContext(database, timeout))
+}
+
+// DatabasePingCheckContext is like DatabasePingCheck, but returns a
+// CheckContext that gives up on the ping once the context is done.
+func DatabasePingCheckContext(database *sql.DB, timeout time.Duration) CheckContext {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 		if database == nil {
 			return fmt.Errorf("database is nil")
@@ -76,9 +101,15 @@ func DatabasePingCheck(database *sql.DB, timeout time.Duration) Check {
 // DNSResolveCheck returns a Check that makes sure the provided host can resolve
 // to at least one IP address within the specified timeout.
 func DNSResolveCheck(host string, timeout time.Duration) Check {
+	return BackgroundCheck(DNSResolveCheckContext(host, timeout))
+}
+
+// DNSResolveCheckContext is like DNSResolveCheck, but returns a CheckContext
+// that gives up on the lookup once the context is done.
+func DNSResolveCheckContext(host string, timeout time.Duration) CheckContext {
 	resolver := net.Resolver{}
-	return func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 		addrs, err := resolver.LookupHost(ctx, host)
 		if err != nil {
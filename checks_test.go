@@ -15,6 +15,10 @@
 package healthcheck
 
 import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"runtime"
 	"testing"
 	"time"
@@ -57,3 +61,45 @@ func TestGCMaxPauseCheck(t *testing.T) {
 	assert.NoError(t, GCMaxPauseCheck(1*time.Second)())
 	assert.Error(t, GCMaxPauseCheck(0)())
 }
+
+func TestTCPDialCheckContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	check := TCPDialCheckContext(listener.Addr().String(), 5*time.Second)
+	assert.NoError(t, check(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, check(ctx), "canceled context should fail")
+}
+
+func TestHTTPGetCheckContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	assert.NoError(t, HTTPGetCheckContext(server.URL, 5*time.Second)(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.Error(t, HTTPGetCheckContext(server.URL+"/slow", 5*time.Second)(ctx), "expired context should fail")
+	assert.True(t, time.Since(start) < time.Second, "expected the request to be canceled with the context")
+}
+
+func TestDatabasePingCheckContext(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	assert.NoError(t, DatabasePingCheckContext(db, 1*time.Second)(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, DatabasePingCheckContext(db, 1*time.Second)(ctx), "canceled context should fail")
+}
@@ -0,0 +1,38 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"context"
+)
+
+// ContextCheck converts a Check into a CheckContext. The check itself can't
+// be canceled, but it is skipped if the context is already done.
+func ContextCheck(check Check) CheckContext {
+	return func(ctx context.Context) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return check()
+	}
+}
+
+// BackgroundCheck converts a CheckContext into a Check that always runs with
+// context.Background().
+func BackgroundCheck(check CheckContext) Check {
+	return func() error {
+		return check(context.Background())
+	}
+}
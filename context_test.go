@@ -0,0 +1,47 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextCheck(t *testing.T) {
+	calls := 0
+	check := ContextCheck(func() error {
+		calls++
+		return errors.New("failed")
+	})
+
+	assert.EqualError(t, check(context.Background()), "failed")
+	assert.Equal(t, 1, calls)
+
+	// a canceled context should skip the check entirely
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, check(ctx))
+	assert.Equal(t, 1, calls)
+}
+
+func TestBackgroundCheck(t *testing.T) {
+	check := BackgroundCheck(func(ctx context.Context) error {
+		return ctx.Err()
+	})
+	assert.NoError(t, check())
+}
@@ -0,0 +1,83 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+// dependencyGraph returns, for every check, the indices of the checks it
+// depends on and of the checks that depend on it. Dependencies on checks that
+// aren't part of checks are ignored.
+func dependencyGraph(checks []*registeredCheck) (dependencies, dependents [][]int) {
+	indices := make(map[string][]int, len(checks))
+	for i, check := range checks {
+		indices[check.name] = append(indices[check.name], i)
+	}
+	dependencies = make([][]int, len(checks))
+	dependents = make([][]int, len(checks))
+	for i, check := range checks {
+		for _, name := range check.dependencies {
+			for _, j := range indices[name] {
+				if j != i {
+					dependencies[i] = append(dependencies[i], j)
+					dependents[j] = append(dependents[j], i)
+				}
+			}
+		}
+	}
+	return dependencies, dependents
+}
+
+// failedDependency returns the name of the first of the dependencies that
+// failed or was skipped, or "" if none did.
+func failedDependency(results []checkResult, dependencies []int) string {
+	for _, i := range dependencies {
+		if status := results[i].status; status == StatusFailed || status == StatusSkipped {
+			return results[i].name
+		}
+	}
+	return ""
+}
+
+// createsCycle returns whether registering c would create a dependency cycle
+// among the registered checks. It must be called with the checks mutex held.
+func (s *basicHandler) createsCycle(c *registeredCheck) bool {
+	// dependencies returns the names of the checks name depends on, across
+	// every kind, as if c was already registered
+	dependencies := func(name string) []string {
+		var names []string
+		for kind, checks := range s.checks {
+			if check, ok := checks[name]; ok && !(kind == c.kind && name == c.name) {
+				names = append(names, check.dependencies...)
+			}
+		}
+		if name == c.name {
+			names = append(names, c.dependencies...)
+		}
+		return names
+	}
+
+	visited := make(map[string]bool)
+	pending := append([]string(nil), c.dependencies...)
+	for len(pending) > 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if name == c.name {
+			return true
+		}
+		if !visited[name] {
+			visited[name] = true
+			pending = append(pending, dependencies(name)...)
+		}
+	}
+	return false
+}
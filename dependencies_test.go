@@ -0,0 +1,130 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependsOn(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	var dnsErr error
+	check := func(name string, err *error) Check {
+		return func() error {
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			if err != nil {
+				return *err
+			}
+			return nil
+		}
+	}
+
+	h := NewHandler()
+	h.AddReadinessCheck("database-ping", check("database-ping", nil), DependsOn("database-dial"))
+	h.AddReadinessCheck("database-dial", check("database-dial", nil), DependsOn("database-dns"))
+	h.AddReadinessCheck("database-dns", check("database-dns", &dnsErr))
+	h.AddReadinessCheck("cache", check("cache", nil))
+
+	// the checks run in dependency order
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Len(t, order, 4)
+	assert.Equal(t, []string{"database-dns", "database-dial", "database-ping"}, without(order, "cache"))
+
+	// when an upstream check fails, the downstream checks are skipped
+	order = nil
+	dnsErr = errors.New("no such host")
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready?full=1", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, []string{"database-dns"}, without(order, "cache"))
+	var report jsonReport
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, StatusFailed, report.Checks["database-dns"].Status)
+	assert.Equal(t, StatusSkipped, report.Checks["database-dial"].Status)
+	assert.Equal(t, "dependency \"database-dns\" failed", report.Checks["database-dial"].Error)
+	assert.Nil(t, report.Checks["database-dial"].Timestamp)
+	assert.Equal(t, StatusSkipped, report.Checks["database-ping"].Status)
+	assert.Equal(t, "dependency \"database-dial\" failed", report.Checks["database-ping"].Error)
+	assert.Equal(t, StatusOK, report.Checks["cache"].Status)
+
+	// dependencies that aren't evaluated are ignored
+	order = nil
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready/database-dial", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, []string{"database-dial"}, order)
+}
+
+func TestDependsOnTimeout(t *testing.T) {
+	h := NewHandler(WithTimeout(20 * time.Millisecond))
+	h.AddReadinessCheckContext("upstream", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	h.AddReadinessCheck("downstream", func() error {
+		return nil
+	}, DependsOn("upstream"))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready?full=1", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	var report jsonReport
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, "timed out after 20ms", report.Checks["upstream"].Error)
+	assert.Equal(t, "timed out after 20ms", report.Checks["downstream"].Error)
+}
+
+func TestDependencyCycles(t *testing.T) {
+	h := NewHandler()
+	noop := func(context.Context) error { return nil }
+	assert.NoError(t, h.Register(Readiness, "a", noop, DependsOn("b")))
+	assert.NoError(t, h.Register(Liveness, "b", noop, DependsOn("c")))
+	assert.Equal(t, ErrDependencyCycle, h.Register(Readiness, "c", noop, DependsOn("a")))
+	assert.Equal(t, ErrDependencyCycle, h.Register(Readiness, "self", noop, DependsOn("self")))
+	assert.Equal(t, ErrDependencyCycle, h.Replace(Liveness, "b", noop, DependsOn("a")))
+	assert.Panics(t, func() {
+		h.AddStartupCheckContext("c", noop, DependsOn("a"))
+	})
+
+	// replacing a check replaces its dependencies
+	assert.NoError(t, h.Replace(Readiness, "a", noop))
+	assert.NoError(t, h.Register(Readiness, "c", noop, DependsOn("a")))
+}
+
+// without returns names without the provided name.
+func without(names []string, name string) []string {
+	var filtered []string
+	for _, n := range names {
+		if n != name {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered
+}
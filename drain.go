@@ -0,0 +1,40 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// drainOverride is the override of the readiness endpoints while draining.
+var drainOverride = &override{name: "draining", reason: "draining"}
+
+func (s *basicHandler) Drain(ctx context.Context, grace time.Duration) <-chan struct{} {
+	atomic.StoreInt32(&s.draining, 1)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+	}()
+	return done
+}
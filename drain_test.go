@@ -0,0 +1,96 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrain(t *testing.T) {
+	h := NewHandler(WithKubernetesEndpoints())
+	readinessCalls := 0
+	h.AddReadinessCheck("database", func() error {
+		readinessCalls++
+		return nil
+	})
+	h.AddLivenessCheck("goroutines", func() error {
+		return nil
+	})
+
+	done := h.Drain(context.Background(), 20*time.Millisecond)
+
+	tests := []struct {
+		path   string
+		expect int
+		body   string
+	}{
+		{path: "/live", expect: http.StatusOK, body: "{}\n"},
+		{path: "/livez", expect: http.StatusOK, body: "ok"},
+		{path: "/ready", expect: http.StatusServiceUnavailable, body: "{}\n"},
+		{path: "/ready/database", expect: http.StatusServiceUnavailable, body: "{}\n"},
+		{path: "/ready?format=health", expect: http.StatusServiceUnavailable, body: "{\n    \"status\": \"fail\",\n    \"output\": \"draining\"\n}\n"},
+		{path: "/readyz", expect: http.StatusServiceUnavailable, body: "[+]database ok\n[+]goroutines ok\n[-]draining failed\nreadyz check failed\n"},
+		{path: "/healthz", expect: http.StatusServiceUnavailable, body: "[+]database ok\n[+]goroutines ok\n[-]draining failed\nhealthz check failed\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+			assert.Equal(t, tt.expect, rr.Code)
+			assert.Equal(t, tt.body, rr.Body.String())
+		})
+	}
+
+	// the checks still run, so the full output stays useful
+	readinessCalls = 0
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready?full=1", nil))
+	assert.Equal(t, 1, readinessCalls)
+	var report jsonReport
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, "draining", report.Reason)
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+
+	select {
+	case <-done:
+		t.Fatal("drain finished before the grace period")
+	default:
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("drain didn't finish after the grace period")
+	}
+}
+
+func TestDrainCanceled(t *testing.T) {
+	h := NewHandler()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := h.Drain(ctx, time.Hour)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("drain didn't finish when its context was canceled")
+	}
+}
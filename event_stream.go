@@ -0,0 +1,178 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// eventStreamBuffer is the number of events buffered for a slow event stream
+// client before new events are dropped.
+const eventStreamBuffer = 64
+
+// streamedEvent is the data of a "status" event in the event stream.
+type streamedEvent struct {
+	Name  string    `json:"name"`
+	Kind  Kind      `json:"kind"`
+	Old   Status    `json:"old,omitempty"`
+	New   Status    `json:"new"`
+	Error string    `json:"error,omitempty"`
+	Time  time.Time `json:"time"`
+}
+
+// streamedSnapshot is the data of a "snapshot" event in the event stream.
+type streamedSnapshot struct {
+	Statuses []streamedStatus `json:"statuses"`
+	Time     time.Time        `json:"time"`
+}
+
+// streamedStatus is the last known status of a check, or of the aggregate
+// status of an endpoint if the name is "".
+type streamedStatus struct {
+	Name   string `json:"name"`
+	Kind   Kind   `json:"kind"`
+	Status Status `json:"status"`
+}
+
+// eventFilter selects the events of a stream from the ?check= and ?kind=
+// query parameters. If checks are selected, aggregate statuses are not.
+type eventFilter struct {
+	names map[string]bool
+	kinds map[Kind]bool
+}
+
+func newEventFilter(r *http.Request) eventFilter {
+	query := r.URL.Query()
+	f := eventFilter{names: make(map[string]bool), kinds: make(map[Kind]bool)}
+	for _, name := range query["check"] {
+		f.names[name] = true
+	}
+	for _, kind := range query["kind"] {
+		f.kinds[Kind(kind)] = true
+	}
+	return f
+}
+
+func (f eventFilter) matches(kind Kind, name string) bool {
+	if len(f.kinds) > 0 && !f.kinds[kind] {
+		return false
+	}
+	return len(f.names) == 0 || f.names[name]
+}
+
+func (s *basicHandler) EventsEndpoint(w http.ResponseWriter, r *http.Request) {
+	if !s.allowsMethod(r.Method) {
+		w.Header().Set("Allow", strings.Join(s.methods, ", "))
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	filter := newEventFilter(r)
+	events := make(chan Event, eventStreamBuffer)
+	cancel := s.OnStatusChange(func(event Event) {
+		if !filter.matches(event.Kind, event.Name) {
+			return
+		}
+		// never block the delivery to other listeners on a slow client
+		select {
+		case events <- event:
+		default:
+		}
+	})
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	s.writeSnapshot(w, filter)
+	flusher.Flush()
+
+	heartbeat, stopHeartbeat := newTicker(s.heartbeatInterval)
+	defer stopHeartbeat()
+	snapshot, stopSnapshot := newTicker(s.snapshotInterval)
+	defer stopSnapshot()
+	for {
+		select {
+		case event := <-events:
+			data := streamedEvent{Name: event.Name, Kind: event.Kind, Old: event.Old, New: event.New, Time: event.Time.UTC()}
+			if event.Err != nil {
+				data.Error = event.Err.Error()
+				if s.redactor != nil {
+					data.Error = s.redactor(event.Name, data.Error)
+				}
+			}
+			writeEvent(w, "status", data)
+		case <-snapshot:
+			s.writeSnapshot(w, filter)
+		case <-heartbeat:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// newTicker returns the channel of a ticker with the provided interval and a
+// function that stops it. An interval <= 0 disables the ticker, returning a
+// nil channel that never receives.
+func newTicker(interval time.Duration) (<-chan time.Time, func()) {
+	if interval <= 0 {
+		return nil, func() {}
+	}
+	ticker := time.NewTicker(interval)
+	return ticker.C, ticker.Stop
+}
+
+// writeSnapshot writes the last known statuses that match filter as a
+// "snapshot" event.
+func (s *basicHandler) writeSnapshot(w http.ResponseWriter, filter eventFilter) {
+	snapshot := streamedSnapshot{Statuses: []streamedStatus{}, Time: time.Now().UTC()}
+	for key, status := range s.tracker.snapshot() {
+		if filter.matches(key.kind, key.name) {
+			snapshot.Statuses = append(snapshot.Statuses, streamedStatus{Name: key.name, Kind: key.kind, Status: status})
+		}
+	}
+	sort.Slice(snapshot.Statuses, func(i, j int) bool {
+		a, b := snapshot.Statuses[i], snapshot.Statuses[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	writeEvent(w, "snapshot", snapshot)
+}
+
+// writeEvent writes a Server-Sent Event with data encoded as JSON.
+func writeEvent(w http.ResponseWriter, event string, data interface{}) {
+	encoded, _ := json.Marshal(data)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, encoded)
+}
@@ -0,0 +1,178 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sseEvent is an event read from an event stream.
+type sseEvent struct {
+	name string
+	data string
+}
+
+// readEvents reads the events of an event stream into a channel, skipping
+// comments other than heartbeats.
+func readEvents(body *bufio.Reader) <-chan sseEvent {
+	events := make(chan sseEvent, 100)
+	go func() {
+		defer close(events)
+		var event sseEvent
+		for {
+			line, err := body.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == ": heartbeat":
+				event.name = "heartbeat"
+			case strings.HasPrefix(line, "event: "):
+				event.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				event.data = strings.TrimPrefix(line, "data: ")
+			case line == "":
+				events <- event
+				event = sseEvent{}
+			}
+		}
+	}()
+	return events
+}
+
+// nextSSEEvent returns the next event named name, skipping others.
+func nextSSEEvent(t *testing.T, events <-chan sseEvent, name string) sseEvent {
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatal("event stream closed")
+			}
+			if event.name == name {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for a %s event", name)
+		}
+	}
+}
+
+func TestEventsEndpoint(t *testing.T) {
+	h := NewHandler(WithEventStreamIntervals(10*time.Millisecond, 50*time.Millisecond))
+	h.AddLivenessCheck("goroutines", func() error {
+		return nil
+	})
+	h.AddReadinessCheck("database", func() error {
+		return errors.New("connection refused")
+	})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/live", nil))
+
+	server := httptest.NewServer(h)
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/events?kind=readiness")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	events := readEvents(bufio.NewReader(resp.Body))
+
+	// the stream starts with a snapshot of the matching statuses
+	var snapshot streamedSnapshot
+	assert.NoError(t, json.Unmarshal([]byte(nextSSEEvent(t, events, "snapshot").data), &snapshot))
+	assert.Equal(t, []streamedStatus{}, snapshot.Statuses)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ready", nil))
+	var event streamedEvent
+	assert.NoError(t, json.Unmarshal([]byte(nextSSEEvent(t, events, "status").data), &event))
+	assert.Equal(t, "database", event.Name)
+	assert.Equal(t, Readiness, event.Kind)
+	assert.Equal(t, StatusFailed, event.New)
+	assert.Equal(t, "connection refused", event.Error)
+	assert.NoError(t, json.Unmarshal([]byte(nextSSEEvent(t, events, "status").data), &event))
+	assert.Equal(t, "", event.Name)
+	assert.Equal(t, Readiness, event.Kind)
+
+	nextSSEEvent(t, events, "heartbeat")
+
+	snapshot = streamedSnapshot{}
+	assert.NoError(t, json.Unmarshal([]byte(nextSSEEvent(t, events, "snapshot").data), &snapshot))
+	assert.Equal(t, []streamedStatus{
+		{Name: "", Kind: Readiness, Status: StatusFailed},
+		{Name: "database", Kind: Readiness, Status: StatusFailed},
+	}, snapshot.Statuses)
+}
+
+func TestEventsEndpointDisabledIntervals(t *testing.T) {
+	h := NewHandler(WithEventStreamIntervals(0, -time.Second))
+	h.AddReadinessCheck("database", func() error {
+		return errors.New("connection refused")
+	})
+
+	server := httptest.NewServer(h)
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/events?check=database")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	events := readEvents(bufio.NewReader(resp.Body))
+
+	// only the initial snapshot and status events are sent
+	nextSSEEvent(t, events, "snapshot")
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ready", nil))
+	nextSSEEvent(t, events, "status")
+	select {
+	case event := <-events:
+		t.Errorf("unexpected %s event", event.name)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestEventsEndpointCheckFilter(t *testing.T) {
+	f := newEventFilter(httptest.NewRequest("GET", "/events?check=database&check=cache", nil))
+	assert.True(t, f.matches(Readiness, "database"))
+	assert.True(t, f.matches(Liveness, "cache"))
+	assert.False(t, f.matches(Readiness, "queue"))
+	assert.False(t, f.matches(Readiness, ""))
+
+	f = newEventFilter(httptest.NewRequest("GET", "/events?kind=liveness&check=cache", nil))
+	assert.True(t, f.matches(Liveness, "cache"))
+	assert.False(t, f.matches(Readiness, "cache"))
+}
+
+func TestEventsEndpointUnauthorized(t *testing.T) {
+	h := NewHandler(WithAuthorizer(BearerToken("s3cr3t")), WithPathPrefix("/health"))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/health/events", nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("POST", "/health/events", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
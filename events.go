@@ -0,0 +1,180 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"sync"
+	"time"
+)
+
+// maxQueuedEvents is the number of events that can wait for delivery before
+// new events are dropped, so that slow listeners can't exhaust memory.
+const maxQueuedEvents = 1024
+
+// Event describes a change of the status of a check, or of the aggregate
+// status of an endpoint.
+type Event struct {
+	// Name is the name of the check, or "" for the aggregate status of the
+	// endpoint that serves Kind.
+	Name string
+
+	Kind Kind
+
+	// Old is the previous status, or "" if this is the first result.
+	Old Status
+	New Status
+
+	// Err is the error returned by the check, if any.
+	Err error
+
+	Time time.Time
+}
+
+// statusKey identifies a check, or the aggregate status of an endpoint if the
+// name is "".
+type statusKey struct {
+	kind Kind
+	name string
+}
+
+// statusNotifier is implemented by handlers that can be notified of results
+// computed outside of them, such as by Async checks.
+type statusNotifier interface {
+	notify(kind Kind, name string, err error)
+}
+
+// listener is a function subscribed with OnStatusChange. It is wrapped so that
+// it can be found again when its subscription is canceled.
+type listener struct {
+	fn func(Event)
+}
+
+// statusTracker remembers the last status of every check and delivers status
+// changes to the listeners. Events are queued and delivered in order by a
+// single goroutine, which only runs while there are events to deliver, so
+// listeners never block probes.
+type statusTracker struct {
+	mutex       sync.Mutex
+	statuses    map[statusKey]Status
+	listeners   []*listener
+	queue       []Event
+	dispatching bool
+}
+
+func newStatusTracker() *statusTracker {
+	return &statusTracker{
+		statuses: make(map[statusKey]Status),
+	}
+}
+
+func (s *basicHandler) OnStatusChange(fn func(Event)) (cancel func()) {
+	t := s.tracker
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	l := &listener{fn: fn}
+	t.listeners = append(t.listeners, l)
+	return func() {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+		for i, existing := range t.listeners {
+			if existing == l {
+				t.listeners = append(t.listeners[:i:i], t.listeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (s *basicHandler) notify(kind Kind, name string, err error) {
+	result := checkResult{name: name, kind: kind, severity: SeverityCritical}
+	s.checksMutex.RLock()
+	if check, ok := s.checks[kind][name]; ok {
+		result.severity = check.severity
+	}
+	s.checksMutex.RUnlock()
+	result.setError(err)
+	s.tracker.record(statusKey{kind: kind, name: name}, result.status, err)
+}
+
+// recordResults records the status of every executed check, and if
+// aggregate is set, the overall status of the endpoint serving kind.
+func (t *statusTracker) recordResults(kind Kind, results []checkResult, aggregate bool) {
+	for _, result := range results {
+		if result.status != StatusExcluded {
+			t.record(statusKey{kind: result.kind, name: result.name}, result.status, result.err)
+		}
+	}
+	if aggregate {
+		t.record(statusKey{kind: kind}, overallStatus(results), nil)
+	}
+}
+
+// forget drops the last status of a check that was removed, so it is no
+// longer listed and starts over if it is added again.
+func (t *statusTracker) forget(kind Kind, name string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.statuses, statusKey{kind: kind, name: name})
+}
+
+// snapshot returns a copy of the last known statuses.
+func (t *statusTracker) snapshot() map[statusKey]Status {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	statuses := make(map[statusKey]Status, len(t.statuses))
+	for key, status := range t.statuses {
+		statuses[key] = status
+	}
+	return statuses
+}
+
+// record updates the status of key, and queues an event if it changed.
+func (t *statusTracker) record(key statusKey, status Status, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	old := t.statuses[key]
+	if old == status {
+		return
+	}
+	t.statuses[key] = status
+	if len(t.listeners) == 0 || len(t.queue) >= maxQueuedEvents {
+		return
+	}
+	t.queue = append(t.queue, Event{Name: key.name, Kind: key.kind, Old: old, New: status, Err: err, Time: time.Now()})
+	if !t.dispatching {
+		t.dispatching = true
+		go t.dispatch()
+	}
+}
+
+// dispatch delivers the queued events until the queue is empty.
+func (t *statusTracker) dispatch() {
+	for {
+		t.mutex.Lock()
+		if len(t.queue) == 0 {
+			t.dispatching = false
+			t.mutex.Unlock()
+			return
+		}
+		event := t.queue[0]
+		t.queue = t.queue[1:]
+		listeners := t.listeners
+		t.mutex.Unlock()
+
+		for _, l := range listeners {
+			l.fn(event)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"errors"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// nextEvent returns the next event received on events, or fails the test.
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for an event")
+		return Event{}
+	}
+}
+
+// assertNoEvent fails the test if an event is received on events.
+func assertNoEvent(t *testing.T, events <-chan Event) {
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event %+v", event)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestOnStatusChange(t *testing.T) {
+	h := NewHandler()
+	var failing int32
+	upstream := errors.New("upstream unavailable")
+	h.AddReadinessCheck("upstream", func() error {
+		if atomic.LoadInt32(&failing) == 1 {
+			return upstream
+		}
+		return nil
+	})
+	h.AddLivenessCheck("goroutines", func() error {
+		return nil
+	})
+	events := make(chan Event, 10)
+	cancel := h.OnStatusChange(func(event Event) {
+		events <- event
+	})
+
+	probe := func(path string) {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	// the first results are reported with no previous status
+	probe("/live")
+	event := nextEvent(t, events)
+	assert.Equal(t, "goroutines", event.Name)
+	assert.Equal(t, Liveness, event.Kind)
+	assert.Equal(t, Status(""), event.Old)
+	assert.Equal(t, StatusOK, event.New)
+	assert.False(t, event.Time.IsZero())
+	event = nextEvent(t, events)
+	assert.Equal(t, "", event.Name)
+	assert.Equal(t, Liveness, event.Kind)
+
+	probe("/ready")
+	event = nextEvent(t, events)
+	assert.Equal(t, "upstream", event.Name)
+	assert.Equal(t, Readiness, event.Kind)
+	assert.Equal(t, Status(""), event.Old)
+	event = nextEvent(t, events)
+	assert.Equal(t, "", event.Name)
+	assert.Equal(t, Readiness, event.Kind)
+	assert.Equal(t, StatusOK, event.New)
+
+	// unchanged results aren't reported
+	probe("/ready")
+	assertNoEvent(t, events)
+
+	// probes of a subset of the checks don't change the aggregate status
+	atomic.StoreInt32(&failing, 1)
+	probe("/ready/upstream")
+	event = nextEvent(t, events)
+	assert.Equal(t, "upstream", event.Name)
+	assert.Equal(t, StatusOK, event.Old)
+	assert.Equal(t, StatusFailed, event.New)
+	assert.Equal(t, upstream, event.Err)
+	assertNoEvent(t, events)
+
+	probe("/ready")
+	event = nextEvent(t, events)
+	assert.Equal(t, "", event.Name)
+	assert.Equal(t, StatusOK, event.Old)
+	assert.Equal(t, StatusFailed, event.New)
+
+	cancel()
+	atomic.StoreInt32(&failing, 0)
+	probe("/ready")
+	assertNoEvent(t, events)
+}
+
+func TestOnStatusChangeRemovedCheck(t *testing.T) {
+	h := NewHandler()
+	h.AddReadinessCheck("upstream", func() error {
+		return nil
+	})
+	events := make(chan Event, 10)
+	h.OnStatusChange(func(event Event) {
+		events <- event
+	})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, "upstream", nextEvent(t, events).Name)
+	assert.Equal(t, "", nextEvent(t, events).Name)
+
+	// removed checks are no longer tracked
+	h.RemoveReadinessCheck("upstream")
+	tracker := h.(*basicHandler).tracker
+	assert.Equal(t, map[statusKey]Status{{kind: Readiness}: StatusOK}, tracker.snapshot())
+
+	// and start over when they are added again
+	h.AddReadinessCheck("upstream", func() error {
+		return nil
+	})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ready", nil))
+	event := nextEvent(t, events)
+	assert.Equal(t, "upstream", event.Name)
+	assert.Equal(t, Status(""), event.Old)
+	assert.Equal(t, StatusOK, event.New)
+}
+
+func TestNotifyHandler(t *testing.T) {
+	h := NewHandler()
+	events := make(chan Event, 10)
+	h.OnStatusChange(func(event Event) {
+		events <- event
+	})
+
+	// the asynchronous check reports its results without being probed
+	h.AddLivenessCheck("async", Async(func() error {
+		return errors.New("failing")
+	}, time.Hour, NotifyHandler(h, Liveness, "async")))
+	event := nextEvent(t, events)
+	assert.Equal(t, "async", event.Name)
+	assert.Equal(t, Liveness, event.Kind)
+	assert.Equal(t, StatusFailed, event.New)
+	assert.EqualError(t, event.Err, "failing")
+}
@@ -15,12 +15,16 @@
 package healthcheck
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/http/httputil"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
@@ -74,8 +78,9 @@ func Example_database() {
 	// Serve http://0.0.0.0:8080/live and http://0.0.0.0:8080/ready endpoints.
 	// go http.ListenAndServe("0.0.0.0:8080", health)
 
-	// Make a request to the readiness endpoint and print the response.
-	fmt.Print(dumpRequest(health, "GET", "/ready?full=1"))
+	// Make a request to the readiness endpoint and print the response in the
+	// flat legacy format (the default format also includes timings).
+	fmt.Print(dumpRequest(health, "GET", "/ready?full=1&format=legacy"))
 
 	// Output:
 	// HTTP/1.1 200 OK
@@ -137,6 +142,23 @@ func Example_advanced() {
 	// {}
 }
 
+func Example_drain() {
+	health := NewHandler()
+	server := &http.Server{Addr: "0.0.0.0:8080", Handler: health}
+	go server.ListenAndServe()
+
+	// When Kubernetes terminates the pod, fail readiness first so endpoints
+	// controllers and load balancers stop routing new requests to it, and
+	// only then shut the server down.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM)
+	go func() {
+		<-signals
+		<-health.Drain(context.Background(), 10*time.Second)
+		server.Shutdown(context.Background())
+	}()
+}
+
 func Example_metrics() {
 	// Create a new Prometheus registry (you'd likely already have one of these).
 	registry := prometheus.NewRegistry()
@@ -173,10 +195,10 @@ func Example_metrics() {
 
 	// Output:
 	// HTTP/1.1 200 OK
-	// Content-Length: 245
+	// Content-Length: 268
 	// Content-Type: text/plain; version=0.0.4; charset=utf-8
 	//
-	// # HELP example_healthcheck_status Current check status (0 indicates success, 1 indicates failure)
+	// # HELP example_healthcheck_status Current check status (0 indicates success, 1 indicates failure, 2 indicates a warning)
 	// # TYPE example_healthcheck_status gauge
 	// example_healthcheck_status{check="failing-check"} 1
 	// example_healthcheck_status{check="successful-check"} 0
@@ -15,89 +15,591 @@
 package healthcheck
 
 import (
-	"encoding/json"
+	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 )
 
 // basicHandler is a basic Handler implementation.
 type basicHandler struct {
 	http.ServeMux
-	checksMutex     sync.RWMutex
-	livenessChecks  map[string]Check
-	readinessChecks map[string]Check
+	checksMutex   sync.RWMutex
+	checks        map[Kind]map[string]*registeredCheck
+	concurrency   int
+	timeout       time.Duration
+	defaultFormat Format
+
+	// livenessAfterStartup skips the liveness checks until every startup
+	// check has passed
+	livenessAfterStartup bool
+
+	// kubernetesEndpoints also serves /livez, /readyz and /healthz
+	kubernetesEndpoints bool
+
+	// pathPrefix is prepended to the paths of every endpoint
+	pathPrefix  string
+	livePath    string
+	readyPath   string
+	startupPath string
+
+	methods      []string
+	successCode  int
+	failureCode  int
+	verboseParam string
+
+	authorizer Authorizer
+	redactor   Redactor
+
+	tracker *statusTracker
+
+	historySize int
+	history     *resultHistory
+
+	// heartbeatInterval and snapshotInterval pace the event stream
+	heartbeatInterval time.Duration
+	snapshotInterval  time.Duration
+
+	logger        *slog.Logger
+	slowThreshold time.Duration
+	results       *resultLogger
+
+	tracer Tracer
+
+	// repanic re-panics with the value of checks that panicked
+	repanic bool
+
+	// draining is set to 1 by Drain
+	draining int32
+
+	// readiness is set by OverrideReadiness
+	readinessMutex sync.Mutex
+	readiness      *override
+}
+
+// registeredCheck is a check along with what the handler knows about it.
+type registeredCheck struct {
+	name     string
+	kind     Kind
+	check    CheckContext
+	tags     []string
+	severity Severity
+
+	// thresholds is only set for checks registered with WithThresholds
+	thresholds *thresholds
+
+	// dependencies are the names of the checks this check depends on
+	dependencies []string
+
+	// latch is only set for startup checks
+	latch *startupCheck
+}
+
+func newRegisteredCheck(kind Kind, name string, check CheckContext, opts []CheckOption) *registeredCheck {
+	c := &registeredCheck{name: name, kind: kind, check: check, severity: SeverityCritical}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// hasTag returns whether the check is tagged with any of the provided tags.
+func (c *registeredCheck) hasTag(tags ...string) bool {
+	for _, tag := range tags {
+		for _, t := range c.tags {
+			if t == tag {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // NewHandler creates a new basic Handler
-func NewHandler() Handler {
+func NewHandler(opts ...Option) Handler {
 	h := &basicHandler{
-		livenessChecks:  make(map[string]Check),
-		readinessChecks: make(map[string]Check),
+		checks: map[Kind]map[string]*registeredCheck{
+			Liveness:  make(map[string]*registeredCheck),
+			Readiness: make(map[string]*registeredCheck),
+			Startup:   make(map[string]*registeredCheck),
+		},
+		defaultFormat: FormatJSON,
+		livePath:      "/live",
+		readyPath:     "/ready",
+		startupPath:   "/startup",
+		methods:       []string{http.MethodGet},
+		successCode:   http.StatusOK,
+		failureCode:   http.StatusServiceUnavailable,
+		verboseParam:  "full",
+		tracker:       newStatusTracker(),
+		tracer:        noopTracer{},
+
+		heartbeatInterval: 15 * time.Second,
+		snapshotInterval:  time.Minute,
+		historySize:       100,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.history = newResultHistory(h.historySize)
+	if h.logger != nil {
+		h.results = newResultLogger(h.logger, h.slowThreshold)
+		h.OnStatusChange(func(event Event) {
+			logTransition(h.logger, event)
+		})
+	}
+	h.handleEndpoint(h.livePath, h.LiveEndpoint)
+	h.handleEndpoint(h.readyPath, h.ReadyEndpoint)
+	h.handleEndpoint(h.startupPath, h.StartupEndpoint)
+	h.Handle(h.pathPrefix+"/events", http.HandlerFunc(h.EventsEndpoint))
+	h.Handle(h.pathPrefix+"/history", http.HandlerFunc(h.HistoryEndpoint))
+	if h.kubernetesEndpoints {
+		h.handleKubernetesEndpoints()
 	}
-	h.Handle("/live", http.HandlerFunc(h.LiveEndpoint))
-	h.Handle("/ready", http.HandlerFunc(h.ReadyEndpoint))
 	return h
 }
 
+// handleEndpoint serves endpoint at path under the path prefix, as well as
+// its single checks at sub-paths of it.
+func (s *basicHandler) handleEndpoint(path string, endpoint http.HandlerFunc) {
+	path = s.pathPrefix + path
+	s.Handle(path, endpoint)
+	s.Handle(path+"/", checkPathHandler(path+"/", endpoint))
+}
+
+// checkPathHandler serves <prefix><name> as if it was requested as
+// <prefix>?check=<name>, so a single check can be used as a probe target.
+func checkPathHandler(prefix string, endpoint http.HandlerFunc) http.Handler {
+	return http.StripPrefix(prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		query.Set("check", r.URL.Path)
+		r.URL.RawQuery = query.Encode()
+		endpoint(w, r)
+	}))
+}
+
 func (s *basicHandler) LiveEndpoint(w http.ResponseWriter, r *http.Request) {
-	s.handle(w, r, s.livenessChecks)
+	s.handle(w, r, s.skipLiveness(), nil, Liveness)
 }
 
 func (s *basicHandler) ReadyEndpoint(w http.ResponseWriter, r *http.Request) {
-	s.handle(w, r, s.readinessChecks, s.livenessChecks)
+	s.handle(w, r, false, s.readinessOverride(), Readiness, Liveness, Startup)
+}
+
+func (s *basicHandler) StartupEndpoint(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, r, false, nil, Startup)
+}
+
+func (s *basicHandler) AddLivenessCheck(name string, check Check, opts ...CheckOption) {
+	s.AddLivenessCheckContext(name, ContextCheck(check), opts...)
+}
+
+func (s *basicHandler) AddReadinessCheck(name string, check Check, opts ...CheckOption) {
+	s.AddReadinessCheckContext(name, ContextCheck(check), opts...)
+}
+
+func (s *basicHandler) AddStartupCheck(name string, check Check, opts ...CheckOption) {
+	s.AddStartupCheckContext(name, ContextCheck(check), opts...)
+}
+
+func (s *basicHandler) AddLivenessCheckContext(name string, check CheckContext, opts ...CheckOption) {
+	s.mustAdd(Liveness, name, check, opts)
+}
+
+func (s *basicHandler) AddReadinessCheckContext(name string, check CheckContext, opts ...CheckOption) {
+	s.mustAdd(Readiness, name, check, opts)
+}
+
+func (s *basicHandler) AddStartupCheckContext(name string, check CheckContext, opts ...CheckOption) {
+	s.mustAdd(Startup, name, check, opts)
+}
+
+func (s *basicHandler) Register(kind Kind, name string, check CheckContext, opts ...CheckOption) error {
+	return s.add(kind, name, check, addOnly, opts)
+}
+
+func (s *basicHandler) Replace(kind Kind, name string, check CheckContext, opts ...CheckOption) error {
+	return s.add(kind, name, check, replaceOnly, opts)
+}
+
+func (s *basicHandler) RemoveLivenessCheck(name string) {
+	s.remove(Liveness, name)
+}
+
+func (s *basicHandler) RemoveReadinessCheck(name string) {
+	s.remove(Readiness, name)
+}
+
+func (s *basicHandler) RemoveStartupCheck(name string) {
+	s.remove(Startup, name)
+}
+
+// addMode controls what add does when a check with the same kind and name is
+// (or isn't) already registered.
+type addMode int
+
+const (
+	addOrReplace addMode = iota
+	addOnly
+	replaceOnly
+)
+
+// mustAdd adds or replaces a check, and panics if it can't, which only
+// happens if it would create a dependency cycle.
+func (s *basicHandler) mustAdd(kind Kind, name string, check CheckContext, opts []CheckOption) {
+	if err := s.add(kind, name, check, addOrReplace, opts); err != nil {
+		panic(fmt.Sprintf("healthcheck: can't add %s check %q: %v", kind, name, err))
+	}
 }
 
-func (s *basicHandler) AddLivenessCheck(name string, check Check) {
+func (s *basicHandler) add(kind Kind, name string, check CheckContext, mode addMode, opts []CheckOption) error {
+	c := newRegisteredCheck(kind, name, check, opts)
+	if kind == Startup {
+		c.latch = &startupCheck{check: check}
+		c.check = c.latch.run
+	}
+
 	s.checksMutex.Lock()
 	defer s.checksMutex.Unlock()
-	s.livenessChecks[name] = check
+	checks, ok := s.checks[kind]
+	if !ok {
+		return fmt.Errorf("unknown check kind %q", kind)
+	}
+	_, exists := checks[name]
+	if exists && mode == addOnly {
+		return ErrDuplicateCheck
+	}
+	if !exists && mode == replaceOnly {
+		return ErrCheckNotFound
+	}
+	if s.createsCycle(c) {
+		return ErrDependencyCycle
+	}
+	checks[name] = c
+	return nil
 }
 
-func (s *basicHandler) AddReadinessCheck(name string, check Check) {
+func (s *basicHandler) remove(kind Kind, name string) {
 	s.checksMutex.Lock()
 	defer s.checksMutex.Unlock()
-	s.readinessChecks[name] = check
+	delete(s.checks[kind], name)
+	s.tracker.forget(kind, name)
+	s.history.forget(kind, name)
 }
 
-func (s *basicHandler) collectChecks(checks map[string]Check, resultsOut map[string]string, statusOut *int) {
+// snapshot returns the currently registered checks of the provided kinds.
+func (s *basicHandler) snapshot(kinds ...Kind) []*registeredCheck {
 	s.checksMutex.RLock()
 	defer s.checksMutex.RUnlock()
-	for name, check := range checks {
-		if err := check(); err != nil {
-			*statusOut = http.StatusServiceUnavailable
-			resultsOut[name] = err.Error()
+	var checks []*registeredCheck
+	for _, kind := range kinds {
+		for _, check := range s.checks[kind] {
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+// checkResult is the outcome of a single check execution.
+type checkResult struct {
+	name     string
+	kind     Kind
+	tags     []string
+	severity Severity
+	status   Status
+	err      error
+	start    time.Time
+	duration time.Duration
+
+	// the streaks are only set for checks registered with WithThresholds
+	consecutiveFailures  int
+	consecutiveSuccesses int
+}
+
+// skip records that the check wasn't executed because the named dependency
+// failed. The result keeps the time the checks were evaluated.
+func (r *checkResult) skip(dependency string) {
+	r.status = StatusSkipped
+	r.err = fmt.Errorf("dependency %q failed", dependency)
+}
+
+// setError records the error returned by the check, if any. Failures of
+// warning checks only count as warnings.
+func (r *checkResult) setError(err error) {
+	r.err = err
+	switch {
+	case err == nil:
+		r.status = StatusOK
+	case r.severity == SeverityWarning:
+		r.status = StatusWarning
+	default:
+		r.status = StatusFailed
+	}
+}
+
+// collectChecks runs the provided checks in parallel, bounded by the
+// configured concurrency limit and overall timeout. It works on a snapshot of
+// the registered checks, so a slow check never blocks adding new checks.
+// Checks only start once the checks they depend on have returned, and are
+// skipped if any of those failed. Thresholds are applied with the streaks of
+// the endpoint kind, which only advance if advance is set.
+func (s *basicHandler) collectChecks(ctx context.Context, checks []*registeredCheck, endpoint Kind, advance bool) []checkResult {
+	// the semaphore is only needed if the concurrency is limited
+	var semaphore chan struct{}
+	if s.concurrency > 0 && s.concurrency < len(checks) {
+		semaphore = make(chan struct{}, s.concurrency)
+	}
+
+	// canceling the context also tells checks still waiting for a slot not
+	// to bother
+	var cancel context.CancelFunc
+	if s.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	// results are sent back over a buffered channel so checks that return
+	// after the deadline don't leak their goroutine
+	type finishedCheck struct {
+		index    int
+		err      error
+		start    time.Time
+		duration time.Duration
+	}
+	finished := make(chan finishedCheck, len(checks))
+	run := func(i int) {
+		go func(i int, check *registeredCheck) {
+			if semaphore != nil {
+				select {
+				case semaphore <- struct{}{}:
+					defer func() { <-semaphore }()
+				case <-ctx.Done():
+					return
+				}
+			}
+			ctx, end := s.startCheck(ctx, check)
+			start := time.Now()
+			err := callCheck(ctx, check.check)
+			duration := time.Since(start)
+			end(err)
+			finished <- finishedCheck{index: i, err: err, start: start, duration: duration}
+		}(i, checks[i])
+	}
+
+	start := time.Now()
+	results := make([]checkResult, len(checks))
+	returned := make([]bool, len(checks))
+	for i, check := range checks {
+		results[i] = checkResult{name: check.name, kind: check.kind, tags: check.tags, severity: check.severity, start: start}
+	}
+
+	// start the checks without dependencies right away, and the others once
+	// their last dependency has returned
+	dependencies, dependents := dependencyGraph(checks)
+	waiting := make([]int, len(checks))
+	for i := range checks {
+		waiting[i] = len(dependencies[i])
+		if waiting[i] == 0 {
+			run(i)
+		}
+	}
+	remaining := len(checks)
+	var finish func(i int)
+	finish = func(i int) {
+		returned[i] = true
+		remaining--
+		for _, j := range dependents[i] {
+			waiting[j]--
+			if waiting[j] > 0 {
+				continue
+			}
+			if failed := failedDependency(results, dependencies[j]); failed != "" {
+				results[j].skip(failed)
+				finish(j)
+			} else {
+				run(j)
+			}
+		}
+	}
+
+	for remaining > 0 {
+		select {
+		case f := <-finished:
+			if p, ok := f.err.(*panicError); ok && s.repanic {
+				panic(p.value)
+			}
+			results[f.index].setError(f.err)
+			results[f.index].start = f.start
+			results[f.index].duration = f.duration
+			checks[f.index].applyThresholds(&results[f.index], endpoint, advance)
+			finish(f.index)
+		case <-ctx.Done():
+			// report every check that hasn't returned yet as timed out (or
+			// canceled, if the client went away)
+			err := ctx.Err()
+			if s.timeout > 0 && err == context.DeadlineExceeded {
+				err = timeoutError(s.timeout)
+			}
+			for i := range results {
+				if !returned[i] {
+					results[i].setError(err)
+					results[i].duration = time.Since(start)
+					checks[i].applyThresholds(&results[i], endpoint, advance)
+				}
+			}
+			return results
+		}
+	}
+	return results
+}
+
+// excludedResults reports the provided checks as excluded.
+func excludedResults(checks []*registeredCheck) []checkResult {
+	results := make([]checkResult, len(checks))
+	for i, check := range checks {
+		results[i] = checkResult{name: check.name, kind: check.kind, tags: check.tags, severity: check.severity, status: StatusExcluded}
+	}
+	return results
+}
+
+// selectChecks narrows the checks down to the ones named by the ?check= query
+// parameters of r and the ones tagged with any of the ?tag= query parameters,
+// if there are any, and then splits off the ones named by the ?exclude= query
+// parameters. It returns an error naming the first requested check or tag that
+// doesn't exist; unknown excluded checks are ignored.
+func selectChecks(r *http.Request, checks []*registeredCheck) (selected, excluded []*registeredCheck, err error) {
+	query := r.URL.Query()
+	selected = checks
+	if names := query["check"]; len(names) > 1 || (len(names) == 1 && names[0] != "") {
+		selected = nil
+		for _, name := range names {
+			found := false
+			for _, check := range checks {
+				if check.name == name {
+					selected = append(selected, check)
+					found = true
+				}
+			}
+			if !found {
+				return nil, nil, fmt.Errorf("check %q not found", name)
+			}
+		}
+	}
+
+	if tags := query["tag"]; len(tags) > 0 {
+		var tagged []*registeredCheck
+		for _, check := range selected {
+			if check.hasTag(tags...) {
+				tagged = append(tagged, check)
+			}
+		}
+		for _, tag := range tags {
+			if !anyHasTag(selected, tag) {
+				return nil, nil, fmt.Errorf("no checks tagged %q", tag)
+			}
+		}
+		selected = tagged
+	}
+
+	exclude := make(map[string]bool)
+	for _, name := range query["exclude"] {
+		exclude[name] = true
+	}
+	if len(exclude) == 0 {
+		return selected, nil, nil
+	}
+	var included []*registeredCheck
+	for _, check := range selected {
+		if exclude[check.name] {
+			excluded = append(excluded, check)
 		} else {
-			resultsOut[name] = "OK"
+			included = append(included, check)
 		}
 	}
+	return included, excluded, nil
 }
 
-func (s *basicHandler) handle(w http.ResponseWriter, r *http.Request, checks ...map[string]Check) {
-	if r.Method != http.MethodGet {
+// anyHasTag returns whether any of the checks is tagged with tag.
+func anyHasTag(checks []*registeredCheck, tag string) bool {
+	for _, check := range checks {
+		if check.hasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipLiveness returns whether the liveness checks should be skipped because
+// startup has not completed yet.
+func (s *basicHandler) skipLiveness() bool {
+	return s.livenessAfterStartup && !s.started()
+}
+
+// evaluate runs the checks of the provided kinds that were selected by r. If
+// skip is set, the checks are still looked up but not executed. If the
+// request can't be served, evaluate writes an error response and returns
+// false.
+func (s *basicHandler) evaluate(w http.ResponseWriter, r *http.Request, skip bool, kinds ...Kind) ([]checkResult, bool) {
+	if !s.allowsMethod(r.Method) {
+		w.Header().Set("Allow", strings.Join(s.methods, ", "))
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
+		return nil, false
 	}
 
-	checkResults := make(map[string]string)
-	status := http.StatusOK
-	for _, checks := range checks {
-		s.collectChecks(checks, checkResults, &status)
+	all := s.snapshot(kinds...)
+	checks, excluded, err := selectChecks(r, all)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if skip {
+		checks, excluded = nil, nil
 	}
 
-	// write out the response code and content type header
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(status)
+	// only requests that evaluate every check decide the aggregate status, and
+	// requests for the details don't advance the threshold streaks
+	aggregate := !skip && len(excluded) == 0 && len(checks) == len(all)
+	advance := r.URL.Query().Get(s.verboseParam) != "1"
 
-	// unless ?full=1, return an empty body. Kubernetes only cares about the
-	// HTTP status code, so we won't waste bytes on the full body.
-	if r.URL.Query().Get("full") != "1" {
-		w.Write([]byte("{}\n"))
-		return
+	ctx, end := s.startEndpoint(r, kinds[0])
+	results := s.collectChecks(ctx, checks, kinds[0], advance)
+	end(results)
+	s.tracker.recordResults(kinds[0], results, aggregate)
+	s.history.record(results)
+	if s.results != nil {
+		s.results.logResults(results)
 	}
+	return append(results, excludedResults(excluded)...), true
+}
 
-	// otherwise, write the JSON body ignoring any encoding errors (which
-	// shouldn't really be possible since we're encoding a map[string]string).
-	encoder := json.NewEncoder(w)
-	encoder.SetIndent("", "    ")
-	encoder.Encode(checkResults)
+// allowsMethod returns whether the endpoints can be requested with method.
+func (s *basicHandler) allowsMethod(method string) bool {
+	for _, m := range s.methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// statusCode returns the HTTP status code of an endpoint with the provided
+// overall status.
+func (s *basicHandler) statusCode(status Status) int {
+	if status == StatusFailed {
+		return s.failureCode
+	}
+	return s.successCode
+}
+
+// handle serves the results of the checks of the provided kinds. If override
+// is set, it decides the outcome instead of the results.
+func (s *basicHandler) handle(w http.ResponseWriter, r *http.Request, skip bool, override *override, kinds ...Kind) {
+	if results, ok := s.evaluate(w, r, skip, kinds...); ok {
+		s.writeResults(w, r, results, override)
+	}
 }
@@ -15,10 +15,14 @@
 package healthcheck
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -78,7 +82,7 @@ func TestNewHandler(t *testing.T) {
 		{
 			name:       "with a failing readiness check, /live should still succeed",
 			method:     "GET",
-			path:       "/live?full=1",
+			path:       "/live?full=1&format=legacy",
 			live:       true,
 			ready:      false,
 			expect:     http.StatusOK,
@@ -87,7 +91,7 @@ func TestNewHandler(t *testing.T) {
 		{
 			name:       "with a failing readiness check, /ready should fail",
 			method:     "GET",
-			path:       "/ready?full=1",
+			path:       "/ready?full=1&format=legacy",
 			live:       true,
 			ready:      false,
 			expect:     http.StatusServiceUnavailable,
@@ -96,7 +100,7 @@ func TestNewHandler(t *testing.T) {
 		{
 			name:       "with a failing liveness check, /live should fail",
 			method:     "GET",
-			path:       "/live?full=1",
+			path:       "/live?full=1&format=legacy",
 			live:       false,
 			ready:      true,
 			expect:     http.StatusServiceUnavailable,
@@ -105,7 +109,7 @@ func TestNewHandler(t *testing.T) {
 		{
 			name:       "with a failing liveness check, /ready should fail",
 			method:     "GET",
-			path:       "/ready?full=1",
+			path:       "/ready?full=1&format=legacy",
 			live:       false,
 			ready:      true,
 			expect:     http.StatusServiceUnavailable,
@@ -151,3 +155,453 @@ func TestNewHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlerRunsChecksConcurrently(t *testing.T) {
+	h := NewHandler()
+	for _, name := range []string{"aaa", "bbb", "ccc", "ddd"} {
+		h.AddReadinessCheck(name, func() error {
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		})
+	}
+
+	start := time.Now()
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, time.Since(start) < 150*time.Millisecond,
+		"expected checks to run in parallel, took %s", time.Since(start))
+}
+
+func TestHandlerConcurrencyLimit(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+
+	h := NewHandler(WithConcurrency(2))
+	for _, name := range []string{"aaa", "bbb", "ccc", "ddd", "eee"} {
+		h.AddReadinessCheck(name, func() error {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, 2, maxRunning, "expected at most 2 checks to run at once")
+}
+
+func TestHandlerTimeout(t *testing.T) {
+	h := NewHandler(WithTimeout(20 * time.Millisecond))
+	h.AddReadinessCheck("fast", func() error {
+		return nil
+	})
+	h.AddReadinessCheck("slow", func() error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready?full=1&format=legacy", nil))
+	assert.True(t, time.Since(start) < 500*time.Millisecond,
+		"expected the probe to give up after the timeout, took %s", time.Since(start))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "{\n    \"fast\": \"OK\",\n    \"slow\": \"timed out after 20ms\"\n}\n", rr.Body.String())
+}
+
+func TestHandlerAddDuringSlowCheck(t *testing.T) {
+	h := NewHandler()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h.AddReadinessCheck("slow", func() error {
+		close(started)
+		<-release
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ready", nil))
+	}()
+	<-started
+
+	// adding a check must not wait for the in-flight probe to finish
+	added := make(chan struct{})
+	go func() {
+		h.AddLivenessCheck("new", func() error { return nil })
+		close(added)
+	}()
+	select {
+	case <-added:
+	case <-time.After(time.Second):
+		t.Error("AddLivenessCheck blocked on a running check")
+	}
+	close(release)
+	<-done
+}
+
+func TestHandlerCheckContext(t *testing.T) {
+	type key struct{}
+
+	h := NewHandler()
+	h.AddReadinessCheckContext("value", func(ctx context.Context) error {
+		if ctx.Value(key{}) != "expected" {
+			return errors.New("request context was not passed to the check")
+		}
+		return nil
+	})
+	h.AddLivenessCheckContext("blocking", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	// the liveness check blocks until the client goes away
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), key{}, "expected"), 20*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest("GET", "/ready?full=1&format=legacy", nil).WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "{\n    \"blocking\": \"context deadline exceeded\",\n    \"value\": \"OK\"\n}\n", rr.Body.String())
+}
+
+func TestHandlerStartupChecks(t *testing.T) {
+	calls := 0
+	warm := false
+	h := NewHandler()
+	h.AddStartupCheck("cache-warmup", func() error {
+		calls++
+		if !warm {
+			return errors.New("cache is still warming up")
+		}
+		return nil
+	})
+
+	probe := func(path string) int {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, probe("/startup"))
+	assert.Equal(t, http.StatusServiceUnavailable, probe("/ready"), "readiness should include startup checks")
+	assert.Equal(t, http.StatusOK, probe("/live"))
+
+	warm = true
+	assert.Equal(t, http.StatusOK, probe("/startup"))
+	assert.Equal(t, 3, calls)
+
+	// once passed, the startup check is latched and never called again
+	warm = false
+	assert.Equal(t, http.StatusOK, probe("/startup"))
+	assert.Equal(t, http.StatusOK, probe("/ready"))
+	assert.Equal(t, 3, calls)
+}
+
+func TestHandlerLivenessAfterStartup(t *testing.T) {
+	started := false
+	h := NewHandler(WithLivenessAfterStartup())
+	h.AddStartupCheck("migrations", func() error {
+		if !started {
+			return errors.New("migrations are still running")
+		}
+		return nil
+	})
+	h.AddLivenessCheck("deadlock", func() error {
+		return errors.New("deadlocked")
+	})
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/live", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "liveness should be suppressed until startup completes")
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/live/deadlock", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "a single liveness check should be suppressed until startup completes")
+
+	started = true
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/startup", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/live", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+func TestHandlerSingleCheck(t *testing.T) {
+	calls := make(map[string]int)
+	var mu sync.Mutex
+	check := func(name string, err error) Check {
+		return func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls[name]++
+			return err
+		}
+	}
+
+	h := NewHandler()
+	h.AddLivenessCheck("deadlock", check("deadlock", nil))
+	h.AddReadinessCheck("database", check("database", errors.New("connection refused")))
+	h.AddReadinessCheck("cache", check("cache", nil))
+
+	tests := []struct {
+		path   string
+		expect int
+		calls  map[string]int
+	}{
+		{path: "/ready/database", expect: http.StatusServiceUnavailable, calls: map[string]int{"database": 1}},
+		{path: "/ready?check=database", expect: http.StatusServiceUnavailable, calls: map[string]int{"database": 1}},
+		{path: "/ready/cache", expect: http.StatusOK, calls: map[string]int{"cache": 1}},
+		{path: "/ready/deadlock", expect: http.StatusOK, calls: map[string]int{"deadlock": 1}},
+		{path: "/live/deadlock", expect: http.StatusOK, calls: map[string]int{"deadlock": 1}},
+		{path: "/live/database", expect: http.StatusNotFound, calls: map[string]int{}},
+		{path: "/ready/unknown", expect: http.StatusNotFound, calls: map[string]int{}},
+		{path: "/ready?check=cache&check=unknown", expect: http.StatusNotFound, calls: map[string]int{}},
+		{path: "/ready?check=cache&check=deadlock", expect: http.StatusOK, calls: map[string]int{"cache": 1, "deadlock": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			calls = make(map[string]int)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+			assert.Equal(t, tt.expect, rr.Code)
+			assert.Equal(t, tt.calls, calls)
+		})
+	}
+
+	// the ?check= parameter also works on a custom mount point
+	rr := httptest.NewRecorder()
+	h.ReadyEndpoint(rr, httptest.NewRequest("GET", "/healthz?check=cache&full=1&format=legacy", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "{\n    \"cache\": \"OK\"\n}\n", rr.Body.String())
+}
+
+func TestHandlerExclude(t *testing.T) {
+	calls := 0
+	h := NewHandler()
+	h.AddReadinessCheck("etcd", func() error {
+		calls++
+		return errors.New("etcd is down")
+	})
+	h.AddReadinessCheck("informer-sync", func() error {
+		calls++
+		return errors.New("informers not synced")
+	})
+	h.AddReadinessCheck("ping", func() error {
+		return nil
+	})
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready?exclude=etcd", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready?exclude=etcd&exclude=informer-sync&exclude=unknown&full=1&format=legacy", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, 0, calls)
+	assert.Equal(t, "{\n    \"etcd\": \"excluded\",\n    \"informer-sync\": \"excluded\",\n    \"ping\": \"OK\"\n}\n", rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready?exclude=etcd&exclude=informer-sync&full=1", nil))
+	var report jsonReport
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, map[Status]int{StatusOK: 1, StatusExcluded: 2}, report.Counts)
+	assert.Equal(t, jsonResult{Status: StatusExcluded, Kind: Readiness}, report.Checks["etcd"])
+}
+
+func TestHandlerRegisterReplaceRemove(t *testing.T) {
+	h := NewHandler()
+	probe := func(path string) int {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		return rr.Code
+	}
+	fail := func(context.Context) error { return errors.New("failed") }
+	pass := func(context.Context) error { return nil }
+
+	assert.NoError(t, h.Register(Readiness, "upstream", fail))
+	assert.Equal(t, ErrDuplicateCheck, h.Register(Readiness, "upstream", pass))
+	assert.Equal(t, http.StatusServiceUnavailable, probe("/ready"))
+
+	// the same name is fine for a different kind
+	assert.NoError(t, h.Register(Liveness, "upstream", pass))
+	assert.Error(t, h.Register(Kind("bogus"), "upstream", pass))
+
+	assert.Equal(t, ErrCheckNotFound, h.Replace(Startup, "upstream", pass))
+	assert.NoError(t, h.Replace(Readiness, "upstream", pass))
+	assert.Equal(t, http.StatusOK, probe("/ready"))
+
+	h.AddStartupCheckContext("warmup", fail)
+	assert.Equal(t, http.StatusServiceUnavailable, probe("/startup"))
+	h.RemoveStartupCheck("warmup")
+	assert.Equal(t, http.StatusOK, probe("/startup"))
+
+	h.RemoveReadinessCheck("upstream")
+	h.RemoveLivenessCheck("upstream")
+	h.RemoveLivenessCheck("never-registered")
+	assert.Equal(t, http.StatusNotFound, probe("/ready/upstream"))
+	assert.NoError(t, h.Register(Readiness, "upstream", fail))
+}
+
+func TestHandlerTags(t *testing.T) {
+	calls := make(map[string]int)
+	var mu sync.Mutex
+	check := func(name string, err error) Check {
+		return func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls[name]++
+			return err
+		}
+	}
+
+	h := NewHandler()
+	h.AddReadinessCheck("database", check("database", nil), WithTags("storage", "critical"))
+	h.AddReadinessCheck("s3", check("s3", errors.New("access denied")), WithTags("storage"))
+	h.AddReadinessCheck("kafka", check("kafka", nil), WithTags("messaging"))
+	h.AddLivenessCheck("goroutines", check("goroutines", nil))
+
+	tests := []struct {
+		path   string
+		expect int
+		calls  map[string]int
+	}{
+		{path: "/ready?tag=critical", expect: http.StatusOK, calls: map[string]int{"database": 1}},
+		{path: "/ready?tag=storage", expect: http.StatusServiceUnavailable, calls: map[string]int{"database": 1, "s3": 1}},
+		{path: "/ready?tag=storage&exclude=s3", expect: http.StatusOK, calls: map[string]int{"database": 1}},
+		{path: "/ready?tag=messaging&tag=critical", expect: http.StatusOK, calls: map[string]int{"database": 1, "kafka": 1}},
+		{path: "/ready?tag=unknown", expect: http.StatusNotFound, calls: map[string]int{}},
+		{path: "/live?tag=storage", expect: http.StatusNotFound, calls: map[string]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			calls = make(map[string]int)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+			assert.Equal(t, tt.expect, rr.Code)
+			assert.Equal(t, tt.calls, calls)
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready?full=1", nil))
+	var report jsonReport
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, []string{"storage", "critical"}, report.Checks["database"].Tags)
+	assert.Empty(t, report.Checks["goroutines"].Tags)
+}
+
+func TestHandlerSeverity(t *testing.T) {
+	h := NewHandler()
+	h.AddReadinessCheck("database", func() error {
+		return nil
+	})
+	h.AddReadinessCheck("recommendations", func() error {
+		return errors.New("recommendations unavailable")
+	}, WithSeverity(SeverityWarning))
+
+	// a failing warning check degrades the endpoint without failing it
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready?full=1", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var report jsonReport
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, map[Status]int{StatusOK: 1, StatusWarning: 1}, report.Counts)
+	assert.Equal(t, StatusWarning, report.Checks["recommendations"].Status)
+	assert.Equal(t, SeverityWarning, report.Checks["recommendations"].Severity)
+	assert.Equal(t, "recommendations unavailable", report.Checks["recommendations"].Error)
+	assert.Empty(t, report.Checks["database"].Severity)
+
+	// a failing critical check still fails the endpoint
+	h.AddReadinessCheck("database", func() error {
+		return errors.New("connection refused")
+	})
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready?full=1", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	report = jsonReport{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, StatusFailed, report.Status)
+
+	// health+json reports warnings as "warn"
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready?format=health&exclude=database", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"status": "warn"`)
+}
+
+func TestHandlerPathsMethodsAndStatusCodes(t *testing.T) {
+	h := NewHandler(
+		WithPathPrefix("/health"),
+		WithLivenessPath("/liveness"),
+		WithReadinessPath("/readiness"),
+		WithMethods(http.MethodGet, http.MethodHead),
+		WithStatusCodes(http.StatusOK, http.StatusInternalServerError),
+		WithVerboseParam("verbose"),
+		WithKubernetesEndpoints(),
+	)
+	h.AddLivenessCheck("goroutines", func() error {
+		return nil
+	})
+	h.AddReadinessCheck("database", func() error {
+		return errors.New("connection refused")
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		expect int
+		body   string
+	}{
+		{method: "GET", path: "/health/liveness", expect: http.StatusOK, body: "{}\n"},
+		{method: "HEAD", path: "/health/liveness", expect: http.StatusOK},
+		{method: "GET", path: "/health/readiness", expect: http.StatusInternalServerError, body: "{}\n"},
+		{method: "GET", path: "/health/readiness/goroutines", expect: http.StatusOK, body: "{}\n"},
+		{method: "GET", path: "/health/readiness?full=1", expect: http.StatusInternalServerError, body: "{}\n"},
+		{method: "GET", path: "/health/startup", expect: http.StatusOK, body: "{}\n"},
+		{method: "GET", path: "/health/readyz", expect: http.StatusInternalServerError},
+		{method: "POST", path: "/health/liveness", expect: http.StatusMethodNotAllowed},
+		{method: "GET", path: "/live", expect: http.StatusNotFound},
+		{method: "GET", path: "/ready", expect: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+			assert.Equal(t, tt.expect, rr.Code)
+			if tt.body != "" {
+				assert.Equal(t, tt.body, rr.Body.String())
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("POST", "/health/liveness", nil))
+	assert.Equal(t, "GET, HEAD", rr.Header().Get("Allow"))
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/health/readiness?verbose=1", nil))
+	var report jsonReport
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, StatusFailed, report.Checks["database"].Status)
+}
@@ -0,0 +1,110 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// healthJSONContentType is the media type defined by
+// https://tools.ietf.org/html/draft-inadarei-api-health-check
+const healthJSONContentType = "application/health+json"
+
+// healthStatus is the status of a check in the application/health+json
+// format.
+type healthStatus string
+
+const (
+	healthPass healthStatus = "pass"
+	healthWarn healthStatus = "warn"
+	healthFail healthStatus = "fail"
+)
+
+// healthReport is the FormatHealthJSON response body.
+type healthReport struct {
+	Status healthStatus             `json:"status"`
+	Output string                   `json:"output,omitempty"`
+	Checks map[string][]healthCheck `json:"checks,omitempty"`
+}
+
+// healthCheck is the FormatHealthJSON result of a single check.
+type healthCheck struct {
+	Status        healthStatus `json:"status"`
+	ObservedValue float64      `json:"observedValue"`
+	ObservedUnit  string       `json:"observedUnit"`
+	Time          time.Time    `json:"time"`
+	Output        string       `json:"output,omitempty"`
+}
+
+// toHealthStatus maps status to the application/health+json format. Skipped
+// checks are reported as "warn": like in the overall status, only the failed
+// dependency that caused them to be skipped counts as a failure.
+func toHealthStatus(status Status) healthStatus {
+	switch status {
+	case StatusOK:
+		return healthPass
+	case StatusWarning, StatusDegraded, StatusSkipped:
+		return healthWarn
+	}
+	return healthFail
+}
+
+// acceptsHealthJSON returns whether the Accept header of r asks for the
+// application/health+json format.
+func acceptsHealthJSON(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == healthJSONContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// newHealthReport maps the results into the application/health+json format.
+// The status is always reported; the individual checks are only included if
+// full is set. Each check is keyed by its name, with the time it took to run
+// as the observed value. If override is set, it decides the status and its
+// reason is the output.
+func newHealthReport(results []checkResult, full bool, override *override) healthReport {
+	report := healthReport{Status: toHealthStatus(override.status(overallStatus(results)))}
+	if override != nil {
+		report.Output = override.reason
+	}
+	if !full {
+		return report
+	}
+	report.Checks = make(map[string][]healthCheck, len(results))
+	for _, result := range results {
+		// excluded checks aren't part of the evaluation
+		if result.status == StatusExcluded {
+			continue
+		}
+		check := healthCheck{
+			Status:        toHealthStatus(result.status),
+			ObservedValue: result.duration.Seconds() * 1000,
+			ObservedUnit:  "ms",
+			Time:          result.start.UTC(),
+		}
+		if result.err != nil {
+			check.Output = result.err.Error()
+		}
+		report.Checks[result.name] = append(report.Checks[result.name], check)
+	}
+	return report
+}
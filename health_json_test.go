@@ -0,0 +1,118 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthJSONFormat(t *testing.T) {
+	h := NewHandler()
+	h.AddLivenessCheck("live", func() error {
+		return nil
+	})
+	h.AddReadinessCheck("ready", func() error {
+		return errors.New("upstream unavailable")
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		status int
+		body   healthReport
+	}{
+		{
+			name:   "query parameter without full=1 only reports the status",
+			path:   "/live?format=health",
+			status: http.StatusOK,
+			body:   healthReport{Status: healthPass},
+		},
+		{
+			name:   "Accept header without full=1 only reports the status",
+			path:   "/ready",
+			accept: "text/html, application/health+json;q=0.9",
+			status: http.StatusServiceUnavailable,
+			body:   healthReport{Status: healthFail},
+		},
+		{
+			name:   "full=1 reports every check",
+			path:   "/ready?full=1&format=health",
+			status: http.StatusServiceUnavailable,
+			body: healthReport{
+				Status: healthFail,
+				Checks: map[string][]healthCheck{
+					"live":  {{Status: healthPass, ObservedUnit: "ms"}},
+					"ready": {{Status: healthFail, ObservedUnit: "ms", Output: "upstream unavailable"}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			assert.Equal(t, tt.status, rr.Code)
+			assert.Equal(t, "application/health+json", rr.Header().Get("Content-Type"))
+
+			var body healthReport
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			for _, checks := range body.Checks {
+				// timings vary from run to run
+				for i := range checks {
+					assert.False(t, checks[i].Time.IsZero())
+					checks[i].Time = time.Time{}
+					checks[i].ObservedValue = 0
+				}
+			}
+			assert.Equal(t, tt.body, body)
+		})
+	}
+}
+
+func TestHealthJSONSkipped(t *testing.T) {
+	h := NewHandler()
+	h.AddReadinessCheck("dns", func() error {
+		return errors.New("no such host")
+	})
+	h.AddReadinessCheck("database", func() error {
+		return nil
+	}, DependsOn("dns"))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready?full=1&format=health", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	var body healthReport
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+
+	// only the failed dependency counts as a failure
+	assert.Equal(t, healthFail, body.Status)
+	assert.Equal(t, healthFail, body.Checks["dns"][0].Status)
+	database := body.Checks["database"][0]
+	assert.Equal(t, healthWarn, database.Status)
+	assert.Equal(t, "dependency \"dns\" failed", database.Output)
+	assert.False(t, database.Time.IsZero())
+}
@@ -0,0 +1,176 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// historyReport is the response body of the /history endpoint.
+type historyReport struct {
+	Checks []checkHistory `json:"checks"`
+}
+
+// checkHistory is the retained results of a single check, oldest first, along
+// with the percentage of them that didn't fail. Results that were skipped
+// because a dependency failed don't count towards the uptime either way, and
+// the uptime is left out if all of them were skipped.
+type checkHistory struct {
+	Name    string         `json:"name"`
+	Kind    Kind           `json:"kind"`
+	Uptime  *float64       `json:"uptime,omitempty"`
+	Results []historyEntry `json:"results"`
+}
+
+// historyEntry is a single retained result.
+type historyEntry struct {
+	Status    Status    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ringBuffer retains the most recent entries up to its capacity.
+type ringBuffer struct {
+	entries []historyEntry
+	next    int
+	full    bool
+}
+
+func (b *ringBuffer) add(entry historyEntry) {
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % len(b.entries)
+	b.full = b.full || b.next == 0
+}
+
+// list returns the retained entries, oldest first.
+func (b *ringBuffer) list() []historyEntry {
+	if !b.full {
+		return append([]historyEntry(nil), b.entries[:b.next]...)
+	}
+	return append(append([]historyEntry(nil), b.entries[b.next:]...), b.entries[:b.next]...)
+}
+
+// resultHistory retains the most recent results of every check.
+type resultHistory struct {
+	size    int
+	mutex   sync.Mutex
+	buffers map[statusKey]*ringBuffer
+}
+
+func newResultHistory(size int) *resultHistory {
+	return &resultHistory{size: size, buffers: make(map[statusKey]*ringBuffer)}
+}
+
+// record retains the results of the checks that were executed.
+func (h *resultHistory) record(results []checkResult) {
+	if h.size <= 0 {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for _, result := range results {
+		if result.status == StatusExcluded {
+			continue
+		}
+		key := statusKey{kind: result.kind, name: result.name}
+		buffer, ok := h.buffers[key]
+		if !ok {
+			buffer = &ringBuffer{entries: make([]historyEntry, h.size)}
+			h.buffers[key] = buffer
+		}
+		entry := historyEntry{Status: result.status, Duration: result.duration.String(), Timestamp: result.start.UTC()}
+		if result.err != nil {
+			entry.Error = result.err.Error()
+		}
+		buffer.add(entry)
+	}
+}
+
+// forget drops the results of a check that was removed.
+func (h *resultHistory) forget(kind Kind, name string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	delete(h.buffers, statusKey{kind: kind, name: name})
+}
+
+// report returns the retained results of the checks matching filter.
+func (h *resultHistory) report(filter eventFilter) historyReport {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	report := historyReport{Checks: []checkHistory{}}
+	for key, buffer := range h.buffers {
+		if !filter.matches(key.kind, key.name) {
+			continue
+		}
+		history := checkHistory{Name: key.name, Kind: key.kind, Results: buffer.list()}
+		up, executed := 0, 0
+		for _, entry := range history.Results {
+			switch entry.Status {
+			case StatusSkipped:
+				continue
+			case StatusFailed:
+			default:
+				up++
+			}
+			executed++
+		}
+		if executed > 0 {
+			uptime := 100 * float64(up) / float64(executed)
+			history.Uptime = &uptime
+		}
+		report.Checks = append(report.Checks, history)
+	}
+	sort.Slice(report.Checks, func(i, j int) bool {
+		a, b := report.Checks[i], report.Checks[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return report
+}
+
+func (s *basicHandler) HistoryEndpoint(w http.ResponseWriter, r *http.Request) {
+	if !s.allowsMethod(r.Method) {
+		w.Header().Set("Allow", strings.Join(s.methods, ", "))
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	report := s.history.report(newEventFilter(r))
+	if s.redactor != nil {
+		for _, check := range report.Checks {
+			for i, entry := range check.Results {
+				if entry.Error != "" {
+					check.Results[i].Error = s.redactor(check.Name, entry.Error)
+				}
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.Encode(report)
+}
@@ -0,0 +1,155 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryEndpoint(t *testing.T) {
+	h := NewHandler(WithHistorySize(3), WithPathPrefix("/health"))
+	var calls int32
+	h.AddReadinessCheck("database", func() error {
+		// fail every other call
+		if atomic.AddInt32(&calls, 1)%2 == 0 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	h.AddLivenessCheck("goroutines", func() error {
+		return nil
+	})
+
+	for i := 0; i < 4; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health/ready?exclude=goroutines", nil))
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health/live", nil))
+
+	history := func(path string) historyReport {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var report historyReport
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+		return report
+	}
+
+	report := history("/health/history")
+	if assert.Len(t, report.Checks, 2) {
+		goroutines := report.Checks[0]
+		assert.Equal(t, "goroutines", goroutines.Name)
+		assert.Equal(t, Liveness, goroutines.Kind)
+		if assert.NotNil(t, goroutines.Uptime) {
+			assert.Equal(t, 100.0, *goroutines.Uptime)
+		}
+		assert.Len(t, goroutines.Results, 1)
+
+		// only the 3 most recent results are retained, oldest first
+		database := report.Checks[1]
+		assert.Equal(t, "database", database.Name)
+		assert.Equal(t, Readiness, database.Kind)
+		if assert.Len(t, database.Results, 3) {
+			assert.Equal(t, StatusFailed, database.Results[0].Status)
+			assert.Equal(t, "connection refused", database.Results[0].Error)
+			assert.Equal(t, StatusOK, database.Results[1].Status)
+			assert.Equal(t, StatusFailed, database.Results[2].Status)
+			assert.False(t, database.Results[0].Timestamp.After(database.Results[2].Timestamp))
+			assert.NotEmpty(t, database.Results[0].Duration)
+		}
+		if assert.NotNil(t, database.Uptime) {
+			assert.InDelta(t, 100.0/3, *database.Uptime, 0.01)
+		}
+	}
+
+	report = history("/health/history?kind=liveness")
+	if assert.Len(t, report.Checks, 1) {
+		assert.Equal(t, "goroutines", report.Checks[0].Name)
+	}
+
+	// removed checks are forgotten
+	h.RemoveReadinessCheck("database")
+	report = history("/health/history?check=database")
+	assert.Empty(t, report.Checks)
+}
+
+func TestHistoryUptimeSkipped(t *testing.T) {
+	h := NewHandler()
+	var calls int32
+	h.AddReadinessCheck("dns", func() error {
+		// fail the first two calls
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			return errors.New("no such host")
+		}
+		return nil
+	})
+	h.AddReadinessCheck("database", func() error {
+		return nil
+	}, DependsOn("dns"))
+
+	report := func() historyReport {
+		return h.(*basicHandler).history.report(newEventFilter(httptest.NewRequest("GET", "/history", nil)))
+	}
+
+	// a check that was only skipped so far has no uptime
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ready", nil))
+	if checks := report().Checks; assert.Len(t, checks, 2) {
+		assert.Equal(t, "database", checks[0].Name)
+		assert.Nil(t, checks[0].Uptime)
+		encoded, err := json.Marshal(checks[0])
+		assert.NoError(t, err)
+		assert.NotContains(t, string(encoded), "uptime")
+	}
+
+	// the skipped results of the database don't count as up or down
+	for i := 0; i < 2; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ready", nil))
+	}
+	if checks := report().Checks; assert.Len(t, checks, 2) {
+		database := checks[0]
+		assert.Equal(t, "database", database.Name)
+		if assert.Len(t, database.Results, 3) {
+			assert.Equal(t, StatusSkipped, database.Results[0].Status)
+			assert.False(t, database.Results[0].Timestamp.IsZero())
+		}
+		if assert.NotNil(t, database.Uptime) {
+			assert.Equal(t, 100.0, *database.Uptime)
+		}
+
+		dns := checks[1]
+		assert.Equal(t, "dns", dns.Name)
+		if assert.NotNil(t, dns.Uptime) {
+			assert.InDelta(t, 100.0/3, *dns.Uptime, 0.01)
+		}
+	}
+}
+
+func TestHistoryDisabled(t *testing.T) {
+	h := NewHandler(WithHistorySize(0))
+	h.AddLivenessCheck("goroutines", func() error {
+		return nil
+	})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/live", nil))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/history", nil))
+	assert.Equal(t, "{\n    \"checks\": []\n}\n", rr.Body.String())
+}
@@ -0,0 +1,117 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// handleKubernetesEndpoints registers the /livez, /readyz and /healthz
+// endpoints enabled by WithKubernetesEndpoints.
+func (s *basicHandler) handleKubernetesEndpoints() {
+	endpoints := []struct {
+		name     string
+		skip     func() bool
+		override func() *override
+		kinds    []Kind
+	}{
+		{name: "livez", skip: s.skipLiveness, kinds: []Kind{Liveness}},
+		{name: "readyz", override: s.readinessOverride, kinds: []Kind{Readiness, Liveness, Startup}},
+		{name: "healthz", override: s.readinessOverride, kinds: []Kind{Readiness, Liveness, Startup}},
+	}
+	for _, endpoint := range endpoints {
+		handler := s.kubernetesEndpoint(endpoint.name, endpoint.skip, endpoint.override, endpoint.kinds...)
+		s.handleEndpoint("/"+endpoint.name, handler)
+	}
+}
+
+// kubernetesEndpoint returns a handler that serves the checks of the provided
+// kinds in the plain text format of the Kubernetes component health
+// endpoints, for example:
+//
+//	[+]ping ok
+//	[-]database failed: reason withheld
+//	readyz check failed
+//
+// If readiness returns an override, it decides the outcome and is listed as an
+// additional line, such as "[-]draining failed".
+func (s *basicHandler) kubernetesEndpoint(name string, skip func() bool, readiness func() *override, kinds ...Kind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		results, ok := s.evaluate(w, r, skip != nil && skip(), kinds...)
+		if !ok {
+			return
+		}
+		var o *override
+		if readiness != nil {
+			o = readiness()
+		}
+		sort.Slice(results, func(i, j int) bool {
+			return results[i].name < results[j].name
+		})
+
+		var body bytes.Buffer
+		for _, result := range results {
+			switch result.status {
+			case StatusOK:
+				fmt.Fprintf(&body, "[+]%s ok\n", result.name)
+			case StatusWarning:
+				fmt.Fprintf(&body, "[+]%s warning: reason withheld\n", result.name)
+			case StatusExcluded:
+				fmt.Fprintf(&body, "[+]%s excluded: ok\n", result.name)
+			case StatusSkipped:
+				fmt.Fprintf(&body, "[-]%s skipped: reason withheld\n", result.name)
+			default:
+				fmt.Fprintf(&body, "[-]%s failed: reason withheld\n", result.name)
+			}
+		}
+
+		if o != nil {
+			if o.ready {
+				fmt.Fprintf(&body, "[+]%s ok\n", o.name)
+			} else {
+				fmt.Fprintf(&body, "[-]%s failed\n", o.name)
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+
+		status := o.status(overallStatus(results))
+		w.WriteHeader(s.statusCode(status))
+
+		// callers that aren't authorized don't get the list of checks
+		authorized := s.authorized(r)
+		if !authorized {
+			body.Reset()
+		}
+
+		// like Kubernetes, always explain failures but only list the passing
+		// checks with ?verbose
+		if status == StatusFailed {
+			fmt.Fprintf(&body, "%s check failed\n", name)
+			body.WriteTo(w)
+			return
+		}
+		if _, verbose := r.URL.Query()["verbose"]; !verbose || !authorized {
+			w.Write([]byte("ok"))
+			return
+		}
+		fmt.Fprintf(&body, "%s check passed\n", name)
+		body.WriteTo(w)
+	}
+}
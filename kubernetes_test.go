@@ -0,0 +1,104 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKubernetesEndpoints(t *testing.T) {
+	h := NewHandler(WithKubernetesEndpoints())
+	h.AddLivenessCheck("ping", func() error {
+		return nil
+	})
+	h.AddReadinessCheck("etcd", func() error {
+		return nil
+	})
+	h.AddReadinessCheck("informer-sync", func() error {
+		return errors.New("informers not synced")
+	})
+	h.AddReadinessCheck("metrics-server", func() error {
+		return errors.New("metrics server unreachable")
+	}, WithSeverity(SeverityWarning))
+
+	tests := []struct {
+		path   string
+		expect int
+		body   string
+	}{
+		{
+			path:   "/livez",
+			expect: http.StatusOK,
+			body:   "ok",
+		},
+		{
+			path:   "/livez?verbose",
+			expect: http.StatusOK,
+			body:   "[+]ping ok\nlivez check passed\n",
+		},
+		{
+			path:   "/readyz",
+			expect: http.StatusServiceUnavailable,
+			body:   "[+]etcd ok\n[-]informer-sync failed: reason withheld\n[+]metrics-server warning: reason withheld\n[+]ping ok\nreadyz check failed\n",
+		},
+		{
+			path:   "/readyz?verbose&exclude=informer-sync",
+			expect: http.StatusOK,
+			body:   "[+]etcd ok\n[+]informer-sync excluded: ok\n[+]metrics-server warning: reason withheld\n[+]ping ok\nreadyz check passed\n",
+		},
+		{
+			path:   "/healthz?exclude=informer-sync",
+			expect: http.StatusOK,
+			body:   "ok",
+		},
+		{
+			path:   "/readyz/etcd",
+			expect: http.StatusOK,
+			body:   "ok",
+		},
+		{
+			path:   "/readyz/informer-sync",
+			expect: http.StatusServiceUnavailable,
+			body:   "[-]informer-sync failed: reason withheld\nreadyz check failed\n",
+		},
+		{
+			path:   "/livez/etcd",
+			expect: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+			assert.Equal(t, tt.expect, rr.Code)
+			if tt.body != "" {
+				assert.Equal(t, "text/plain; charset=utf-8", rr.Header().Get("Content-Type"))
+				assert.Equal(t, tt.body, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestKubernetesEndpointsDisabled(t *testing.T) {
+	h := NewHandler()
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,135 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// failureLogInterval is how often the failures of a single check are logged.
+// Failures in between are counted and reported with the next log entry.
+const failureLogInterval = time.Minute
+
+// resultLogger logs failed, slow and timed out check executions, limiting
+// each check to one entry per failureLogInterval.
+type resultLogger struct {
+	logger        *slog.Logger
+	slowThreshold time.Duration
+
+	mutex  sync.Mutex
+	limits map[statusKey]*logLimit
+}
+
+// logLimit is the last time a check was logged, and how many entries have
+// been suppressed since.
+type logLimit struct {
+	last       time.Time
+	suppressed int
+}
+
+func newResultLogger(logger *slog.Logger, slowThreshold time.Duration) *resultLogger {
+	return &resultLogger{logger: logger, slowThreshold: slowThreshold, limits: make(map[statusKey]*logLimit)}
+}
+
+// log logs the result of an execution of a check if it failed or was slow.
+func (l *resultLogger) log(kind Kind, name string, err error, duration time.Duration) {
+	slow := l.slowThreshold > 0 && duration >= l.slowThreshold
+	if err == nil && !slow {
+		return
+	}
+	suppressed, ok := l.allow(statusKey{kind: kind, name: name})
+	if !ok {
+		return
+	}
+
+	attrs := []any{slog.String("check", name)}
+	if kind != "" {
+		attrs = append(attrs, slog.String("kind", string(kind)))
+	}
+	attrs = append(attrs, slog.Duration("duration", duration))
+	if suppressed > 0 {
+		attrs = append(attrs, slog.Int("suppressed", suppressed))
+	}
+	switch {
+	case isTimeout(err):
+		l.logger.Warn("check timed out", append(attrs, slog.Any("error", err))...)
+	case err != nil:
+		l.logger.Warn("check failed", append(attrs, slog.Any("error", err))...)
+	default:
+		l.logger.Warn("slow check", attrs...)
+	}
+}
+
+// logResults logs the results of the checks that were executed.
+func (l *resultLogger) logResults(results []checkResult) {
+	for _, result := range results {
+		if result.status != StatusExcluded && result.status != StatusSkipped {
+			l.log(result.kind, result.name, result.err, result.duration)
+		}
+	}
+}
+
+// allow returns whether the check identified by key may be logged now, and
+// if so, how many entries were suppressed since it was last logged.
+func (l *resultLogger) allow(key statusKey) (suppressed int, ok bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	limit, exists := l.limits[key]
+	if !exists {
+		limit = &logLimit{}
+		l.limits[key] = limit
+	}
+	now := time.Now()
+	if exists && now.Sub(limit.last) < failureLogInterval {
+		limit.suppressed++
+		return 0, false
+	}
+	suppressed = limit.suppressed
+	limit.last, limit.suppressed = now, 0
+	return suppressed, true
+}
+
+// logTransition logs a status change. Transitions are never rate limited.
+func logTransition(logger *slog.Logger, event Event) {
+	level := slog.LevelInfo
+	if event.New == StatusFailed {
+		level = slog.LevelWarn
+	}
+	msg := "check status changed"
+	attrs := []any{slog.String("check", event.Name)}
+	if event.Name == "" {
+		msg = "endpoint status changed"
+		attrs = nil
+	}
+	if event.Kind != "" {
+		attrs = append(attrs, slog.String("kind", string(event.Kind)))
+	}
+	attrs = append(attrs, slog.String("old", string(event.Old)), slog.String("new", string(event.New)))
+	if event.Err != nil {
+		attrs = append(attrs, slog.Any("error", event.Err))
+	}
+	logger.Log(context.Background(), level, msg, attrs...)
+}
+
+// isTimeout returns whether err reports that a check timed out.
+func isTimeout(err error) bool {
+	if _, ok := err.(timeoutError); ok {
+		return true
+	}
+	return err == context.DeadlineExceeded
+}
@@ -0,0 +1,172 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// logBuffer collects JSON log entries from several goroutines.
+type logBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+// entries returns the logged entries with the provided message.
+func (b *logBuffer) entries(t *testing.T, msg string) []map[string]interface{} {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(b.buffer.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+		if entry["msg"] == msg {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// waitForEntries waits until count entries with the provided message have
+// been logged, and returns them.
+func (b *logBuffer) waitForEntries(t *testing.T, msg string, count int) []map[string]interface{} {
+	deadline := time.Now().Add(time.Second)
+	for {
+		entries := b.entries(t, msg)
+		if len(entries) >= count || time.Now().After(deadline) {
+			return entries
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	var logs logBuffer
+	h := NewHandler(
+		WithLogger(slog.New(slog.NewJSONHandler(&logs, nil))),
+		WithSlowCheckThreshold(10*time.Millisecond),
+		WithTimeout(50*time.Millisecond),
+	)
+	h.AddReadinessCheck("database", func() error {
+		return errors.New("connection refused")
+	})
+	h.AddReadinessCheck("cache", func() error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+	h.AddReadinessCheck("queue", func() error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ready", nil))
+	}
+
+	// repeated failures are only logged once
+	failed := logs.entries(t, "check failed")
+	if assert.Len(t, failed, 1) {
+		assert.Equal(t, "WARN", failed[0]["level"])
+		assert.Equal(t, "database", failed[0]["check"])
+		assert.Equal(t, "readiness", failed[0]["kind"])
+		assert.Equal(t, "connection refused", failed[0]["error"])
+		assert.Contains(t, failed[0], "duration")
+	}
+	slow := logs.entries(t, "slow check")
+	if assert.Len(t, slow, 1) {
+		assert.Equal(t, "cache", slow[0]["check"])
+	}
+	timedOut := logs.entries(t, "check timed out")
+	if assert.Len(t, timedOut, 1) {
+		assert.Equal(t, "queue", timedOut[0]["check"])
+		assert.Equal(t, "timed out after 50ms", timedOut[0]["error"])
+	}
+
+	// transitions are logged as they happen
+	changed := logs.waitForEntries(t, "check status changed", 3)
+	assert.Len(t, changed, 3)
+	endpoint := logs.waitForEntries(t, "endpoint status changed", 1)
+	if assert.Len(t, endpoint, 1) {
+		assert.Equal(t, "WARN", endpoint[0]["level"])
+		assert.Equal(t, "readiness", endpoint[0]["kind"])
+		assert.Equal(t, "failed", endpoint[0]["new"])
+	}
+}
+
+func TestResultLoggerSuppressed(t *testing.T) {
+	var logs logBuffer
+	l := newResultLogger(slog.New(slog.NewJSONHandler(&logs, nil)), 0)
+	for i := 0; i < 4; i++ {
+		l.log(Liveness, "goroutines", errors.New("too many goroutines"), time.Millisecond)
+	}
+
+	// once the interval has passed, the number of suppressed entries is logged
+	l.limits[statusKey{kind: Liveness, name: "goroutines"}].last = time.Now().Add(-failureLogInterval)
+	l.log(Liveness, "goroutines", errors.New("too many goroutines"), time.Millisecond)
+	failed := logs.entries(t, "check failed")
+	if assert.Len(t, failed, 2) {
+		assert.NotContains(t, failed[0], "suppressed")
+		assert.Equal(t, 3.0, failed[1]["suppressed"])
+	}
+}
+
+func TestLogTo(t *testing.T) {
+	var logs logBuffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	err := Timeout(func() error {
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	}, time.Millisecond, LogTo(logger, "slow-upstream"))()
+	assert.Error(t, err)
+	timedOut := logs.entries(t, "check timed out")
+	if assert.Len(t, timedOut, 1) {
+		assert.Equal(t, "slow-upstream", timedOut[0]["check"])
+		assert.NotContains(t, timedOut[0], "kind")
+	}
+
+	Async(func() error {
+		return errors.New("unreachable")
+	}, time.Hour, LogTo(logger, "async-upstream"))
+	failed := logs.waitForEntries(t, "check failed", 1)
+	if assert.Len(t, failed, 1) {
+		assert.Equal(t, "async-upstream", failed[0]["check"])
+	}
+	changed := logs.waitForEntries(t, "check status changed", 2)
+	if assert.Len(t, changed, 2) {
+		assert.Equal(t, "slow-upstream", changed[0]["check"])
+		assert.Equal(t, "async-upstream", changed[1]["check"])
+		assert.Equal(t, "failed", changed[1]["new"])
+	}
+}
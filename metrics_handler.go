@@ -15,33 +15,159 @@
 package healthcheck
 
 import (
+	"context"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 type metricsHandler struct {
-	handler   Handler
-	registry  prometheus.Registerer
-	namespace string
+	handler  Handler
+	exporter MetricsExporter
+
+	// exports tracks every exported check, so its metrics can be removed when
+	// the check is removed or replaced
+	exportsMutex sync.Mutex
+	exports      map[metricsKey]exportedCheck
+}
+
+type metricsKey struct {
+	kind Kind
+	name string
+}
+
+// exportedCheck is an instrumented check, its options and how to stop
+// exporting it.
+type exportedCheck struct {
+	check    CheckContext
+	opts     []CheckOption
+	unexport func()
+}
+
+// MetricsExporter reports the status of checks to a metrics system, for use
+// with NewMetricsExporterHandler. The checks and status functions it is passed
+// never panic.
+type MetricsExporter interface {
+	// Instrument returns the check to register in place of check, which may
+	// record metrics about its executions.
+	Instrument(kind Kind, name string, check CheckContext) CheckContext
+
+	// Export starts reporting the status of the check, and returns a function
+	// that stops it. Calling status runs the check and returns the value to
+	// report: 0 for success, 1 for failure and 2 for a failing check with
+	// SeverityWarning.
+	Export(kind Kind, name string, status func(ctx context.Context) int) (unexport func(), err error)
 }
 
 // NewMetricsHandler returns a healthcheck Handler that also exposes metrics
-// into the provided Prometheus registry.
-func NewMetricsHandler(registry prometheus.Registerer, namespace string) Handler {
+// into the provided Prometheus registry. Any options are passed through to
+// NewHandler.
+func NewMetricsHandler(registry prometheus.Registerer, namespace string, opts ...Option) Handler {
+	return NewMetricsExporterHandler(&prometheusExporter{registry: registry, namespace: namespace}, opts...)
+}
+
+// NewMetricsExporterHandler returns a healthcheck Handler that also reports
+// the status of its checks with the provided MetricsExporter, for metrics
+// systems other than Prometheus. Any options are passed through to
+// NewHandler.
+func NewMetricsExporterHandler(exporter MetricsExporter, opts ...Option) Handler {
 	return &metricsHandler{
-		handler:   NewHandler(),
-		registry:  registry,
-		namespace: namespace,
+		handler:  NewHandler(opts...),
+		exporter: exporter,
+		exports:  make(map[metricsKey]exportedCheck),
 	}
 }
 
-func (h *metricsHandler) AddLivenessCheck(name string, check Check) {
-	h.handler.AddLivenessCheck(name, h.wrap(name, check))
+func (h *metricsHandler) AddLivenessCheck(name string, check Check, opts ...CheckOption) {
+	h.AddLivenessCheckContext(name, ContextCheck(check), opts...)
 }
 
-func (h *metricsHandler) AddReadinessCheck(name string, check Check) {
-	h.handler.AddReadinessCheck(name, h.wrap(name, check))
+func (h *metricsHandler) AddReadinessCheck(name string, check Check, opts ...CheckOption) {
+	h.AddReadinessCheckContext(name, ContextCheck(check), opts...)
+}
+
+func (h *metricsHandler) AddStartupCheck(name string, check Check, opts ...CheckOption) {
+	h.AddStartupCheckContext(name, ContextCheck(check), opts...)
+}
+
+func (h *metricsHandler) AddLivenessCheckContext(name string, check CheckContext, opts ...CheckOption) {
+	h.add(Liveness, name, check, opts, h.handler.AddLivenessCheckContext)
+}
+
+func (h *metricsHandler) AddReadinessCheckContext(name string, check CheckContext, opts ...CheckOption) {
+	h.add(Readiness, name, check, opts, h.handler.AddReadinessCheckContext)
+}
+
+func (h *metricsHandler) AddStartupCheckContext(name string, check CheckContext, opts ...CheckOption) {
+	h.add(Startup, name, check, opts, h.handler.AddStartupCheckContext)
+}
+
+func (h *metricsHandler) Register(kind Kind, name string, check CheckContext, opts ...CheckOption) error {
+	check = h.instrument(kind, name, check)
+	if err := h.handler.Register(kind, name, check, opts...); err != nil {
+		return err
+	}
+	unexport, err := h.export(kind, name, check, opts)
+	if err != nil {
+		removeCheck(h.handler, kind, name)
+		return err
+	}
+	h.track(kind, name, exportedCheck{check: check, opts: opts, unexport: unexport})
+	return nil
+}
+
+func (h *metricsHandler) Replace(kind Kind, name string, check CheckContext, opts ...CheckOption) error {
+	check = h.instrument(kind, name, check)
+	if err := h.handler.Replace(kind, name, check, opts...); err != nil {
+		return err
+	}
+	previous, _ := h.untrack(kind, name)
+	unexport, err := h.export(kind, name, check, opts)
+	if err != nil {
+		h.restore(kind, name, previous)
+		return err
+	}
+	h.track(kind, name, exportedCheck{check: check, opts: opts, unexport: unexport})
+	return nil
+}
+
+func (h *metricsHandler) RemoveLivenessCheck(name string) {
+	h.handler.RemoveLivenessCheck(name)
+	h.untrack(Liveness, name)
+}
+
+func (h *metricsHandler) RemoveReadinessCheck(name string) {
+	h.handler.RemoveReadinessCheck(name)
+	h.untrack(Readiness, name)
+}
+
+func (h *metricsHandler) RemoveStartupCheck(name string) {
+	h.handler.RemoveStartupCheck(name)
+	h.untrack(Startup, name)
+}
+
+func (h *metricsHandler) Drain(ctx context.Context, grace time.Duration) <-chan struct{} {
+	return h.handler.Drain(ctx, grace)
+}
+
+func (h *metricsHandler) OverrideReadiness(ready bool, reason string) {
+	h.handler.OverrideReadiness(ready, reason)
+}
+
+func (h *metricsHandler) ClearReadinessOverride() {
+	h.handler.ClearReadinessOverride()
+}
+
+func (h *metricsHandler) OnStatusChange(listener func(Event)) (cancel func()) {
+	return h.handler.OnStatusChange(listener)
+}
+
+func (h *metricsHandler) notify(kind Kind, name string, err error) {
+	if notifier, ok := h.handler.(statusNotifier); ok {
+		notifier.notify(kind, name, err)
+	}
 }
 
 func (h *metricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -56,21 +182,158 @@ func (h *metricsHandler) ReadyEndpoint(w http.ResponseWriter, r *http.Request) {
 	h.handler.ReadyEndpoint(w, r)
 }
 
-func (h *metricsHandler) wrap(name string, check Check) Check {
-	h.registry.MustRegister(prometheus.NewGaugeFunc(
+func (h *metricsHandler) StartupEndpoint(w http.ResponseWriter, r *http.Request) {
+	h.handler.StartupEndpoint(w, r)
+}
+
+func (h *metricsHandler) EventsEndpoint(w http.ResponseWriter, r *http.Request) {
+	h.handler.EventsEndpoint(w, r)
+}
+
+func (h *metricsHandler) HistoryEndpoint(w http.ResponseWriter, r *http.Request) {
+	h.handler.HistoryEndpoint(w, r)
+}
+
+func (h *metricsHandler) OverrideEndpoint(w http.ResponseWriter, r *http.Request) {
+	h.handler.OverrideEndpoint(w, r)
+}
+
+// add instruments the check, adds it with addCheck and exports it, replacing
+// the metrics of any previous check with the same kind and name. The check is
+// only exported once addCheck has accepted it, so a check that panics on a
+// dependency cycle leaves the metrics as they were. It panics if the check
+// can't be exported.
+func (h *metricsHandler) add(kind Kind, name string, check CheckContext, opts []CheckOption, addCheck func(string, CheckContext, ...CheckOption)) {
+	check = h.instrument(kind, name, check)
+	addCheck(name, check, opts...)
+	h.untrack(kind, name)
+	unexport, err := h.export(kind, name, check, opts)
+	if err != nil {
+		panic(err)
+	}
+	h.track(kind, name, exportedCheck{check: check, opts: opts, unexport: unexport})
+}
+
+// restore puts back the previous check with the provided kind and name, and
+// its metrics, after its replacement couldn't be exported.
+func (h *metricsHandler) restore(kind Kind, name string, previous exportedCheck) {
+	if previous.check == nil {
+		removeCheck(h.handler, kind, name)
+		return
+	}
+	h.handler.Replace(kind, name, previous.check, previous.opts...)
+	if unexport, err := h.export(kind, name, previous.check, previous.opts); err == nil {
+		previous.unexport = unexport
+		h.track(kind, name, previous)
+	}
+}
+
+// instrument recovers the panics of the check, lets the exporter instrument
+// it, and latches startup checks so their metrics don't keep running them
+// after startup has completed.
+func (h *metricsHandler) instrument(kind Kind, name string, check CheckContext) CheckContext {
+	recovered := func(ctx context.Context) error {
+		return callCheck(ctx, check)
+	}
+	return latchStartupCheck(kind, h.exporter.Instrument(kind, name, recovered))
+}
+
+// export lets the exporter report the status of the check with the provided
+// options.
+func (h *metricsHandler) export(kind Kind, name string, check CheckContext, opts []CheckOption) (func(), error) {
+	severity := checkSeverity(opts)
+	return h.exporter.Export(kind, name, func(ctx context.Context) int {
+		return statusValue(check(ctx), severity)
+	})
+}
+
+func (h *metricsHandler) track(kind Kind, name string, exported exportedCheck) {
+	h.exportsMutex.Lock()
+	defer h.exportsMutex.Unlock()
+	h.exports[metricsKey{kind: kind, name: name}] = exported
+}
+
+// untrack stops exporting the check with the provided kind and name, if it is
+// exported, and returns it.
+func (h *metricsHandler) untrack(kind Kind, name string) (exportedCheck, bool) {
+	h.exportsMutex.Lock()
+	defer h.exportsMutex.Unlock()
+	key := metricsKey{kind: kind, name: name}
+	exported, ok := h.exports[key]
+	if ok {
+		exported.unexport()
+		delete(h.exports, key)
+	}
+	return exported, ok
+}
+
+// checkSeverity returns the severity the provided options give a check.
+func checkSeverity(opts []CheckOption) Severity {
+	return newRegisteredCheck("", "", nil, opts).severity
+}
+
+// statusValue is the value the status gauges report for a check that
+// returned err: 0 for success, 1 for failure and 2 for a failing check with
+// SeverityWarning.
+func statusValue(err error, severity Severity) int {
+	switch {
+	case err == nil:
+		return 0
+	case severity == SeverityWarning:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// prometheusExporter exports the status of every check as a gauge that runs
+// the check whenever the registry is scraped.
+type prometheusExporter struct {
+	registry  prometheus.Registerer
+	namespace string
+}
+
+func (e *prometheusExporter) Instrument(kind Kind, name string, check CheckContext) CheckContext {
+	return check
+}
+
+func (e *prometheusExporter) Export(kind Kind, name string, status func(context.Context) int) (func(), error) {
+	gauge := prometheus.NewGaugeFunc(
 		prometheus.GaugeOpts{
-			Namespace:   h.namespace,
+			Namespace:   e.namespace,
 			Subsystem:   "healthcheck",
 			Name:        "status",
-			Help:        "Current check status (0 indicates success, 1 indicates failure)",
+			Help:        "Current check status (0 indicates success, 1 indicates failure, 2 indicates a warning)",
 			ConstLabels: prometheus.Labels{"check": name},
 		},
 		func() float64 {
-			if check() == nil {
-				return 0
-			}
-			return 1
+			return float64(status(context.Background()))
 		},
-	))
-	return check
+	)
+	if err := e.registry.Register(gauge); err != nil {
+		return nil, err
+	}
+	return func() { e.registry.Unregister(gauge) }, nil
+}
+
+// latchStartupCheck latches startup checks so they stop running once they
+// have passed.
+func latchStartupCheck(kind Kind, check CheckContext) CheckContext {
+	if kind != Startup {
+		return check
+	}
+	latched := &startupCheck{check: check}
+	return latched.run
+}
+
+// removeCheck calls the Remove method of handler that matches kind.
+func removeCheck(handler Handler, kind Kind, name string) {
+	switch kind {
+	case Liveness:
+		handler.RemoveLivenessCheck(name)
+	case Readiness:
+		handler.RemoveReadinessCheck(name)
+	case Startup:
+		handler.RemoveStartupCheck(name)
+	}
 }
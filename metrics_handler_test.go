@@ -15,6 +15,7 @@
 package healthcheck
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -96,6 +97,12 @@ func TestNewMetricsHandlerEndpoints(t *testing.T) {
 			handler: handler,
 			expect:  503,
 		},
+		{
+			name:    "default /startup endpoint",
+			path:    "/startup",
+			handler: handler,
+			expect:  200,
+		},
 		{
 			name:    "custom /live endpoint",
 			path:    "/",
@@ -121,3 +128,133 @@ func TestNewMetricsHandlerEndpoints(t *testing.T) {
 		})
 	}
 }
+
+func TestNewMetricsHandlerRemoveAndReplace(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	handler := NewMetricsHandler(registry, "test")
+
+	gauges := func() map[string]float64 {
+		families, err := registry.Gather()
+		assert.NoError(t, err)
+		values := make(map[string]float64)
+		for _, family := range families {
+			for _, metric := range family.GetMetric() {
+				values[metric.GetLabel()[0].GetValue()] = metric.GetGauge().GetValue()
+			}
+		}
+		return values
+	}
+
+	handler.AddReadinessCheck("aaa", func() error {
+		return nil
+	})
+	assert.NoError(t, handler.Register(Liveness, "bbb", func(context.Context) error {
+		return nil
+	}))
+	assert.Equal(t, ErrDuplicateCheck, handler.Register(Liveness, "bbb", func(context.Context) error {
+		return nil
+	}))
+	assert.Equal(t, map[string]float64{"aaa": 0, "bbb": 0}, gauges())
+
+	// replacing a check swaps out its gauge
+	assert.NoError(t, handler.Replace(Liveness, "bbb", func(context.Context) error {
+		return fmt.Errorf("failing")
+	}))
+	assert.Equal(t, map[string]float64{"aaa": 0, "bbb": 1}, gauges())
+
+	// adding a check with an existing name doesn't panic on the registry
+	handler.AddReadinessCheck("aaa", func() error {
+		return fmt.Errorf("failing")
+	})
+	assert.Equal(t, map[string]float64{"aaa": 1, "bbb": 1}, gauges())
+
+	handler.RemoveReadinessCheck("aaa")
+	handler.RemoveLivenessCheck("bbb")
+	assert.Equal(t, map[string]float64{}, gauges())
+
+	// failing warning checks report 2
+	assert.NoError(t, handler.Register(Readiness, "ccc", func(context.Context) error {
+		return fmt.Errorf("failing")
+	}, WithSeverity(SeverityWarning)))
+	assert.Equal(t, map[string]float64{"ccc": 2}, gauges())
+	handler.RemoveReadinessCheck("ccc")
+
+	// a removed check can be registered again
+	assert.NoError(t, handler.Register(Readiness, "aaa", func(context.Context) error {
+		return nil
+	}))
+	assert.Equal(t, map[string]float64{"aaa": 0}, gauges())
+}
+
+func TestNewMetricsHandlerCycle(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	handler := NewMetricsHandler(registry, "test")
+	handler.AddReadinessCheck("aaa", func() error {
+		return nil
+	}, DependsOn("bbb"))
+	handler.AddReadinessCheck("bbb", func() error {
+		return nil
+	})
+
+	// a check rejected for a cycle keeps the gauge of the check it would
+	// have replaced
+	assert.Panics(t, func() {
+		handler.AddReadinessCheck("bbb", func() error {
+			return fmt.Errorf("failing")
+		}, DependsOn("aaa"))
+	})
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	if assert.Len(t, families, 1) && assert.Len(t, families[0].GetMetric(), 2) {
+		for _, metric := range families[0].GetMetric() {
+			assert.Equal(t, 0.0, metric.GetGauge().GetValue(), metric.GetLabel()[0].GetValue())
+		}
+	}
+}
+
+// failingExporter is a MetricsExporter that fails the next failures exports.
+type failingExporter struct {
+	failures int
+	exported map[string]func(context.Context) int
+}
+
+func (e *failingExporter) Instrument(kind Kind, name string, check CheckContext) CheckContext {
+	return check
+}
+
+func (e *failingExporter) Export(kind Kind, name string, status func(context.Context) int) (func(), error) {
+	if e.failures > 0 {
+		e.failures--
+		return nil, fmt.Errorf("can't export %s", name)
+	}
+	e.exported[name] = status
+	return func() { delete(e.exported, name) }, nil
+}
+
+func TestNewMetricsExporterHandlerReplaceFails(t *testing.T) {
+	exporter := &failingExporter{exported: make(map[string]func(context.Context) int)}
+	handler := NewMetricsExporterHandler(exporter)
+	assert.NoError(t, handler.Register(Readiness, "aaa", func(context.Context) error {
+		return nil
+	}))
+
+	// a replacement that can't be exported leaves the previous check in place
+	exporter.failures = 1
+	assert.EqualError(t, handler.Replace(Readiness, "aaa", func(context.Context) error {
+		return fmt.Errorf("failing")
+	}), "can't export aaa")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	if assert.Contains(t, exporter.exported, "aaa") {
+		assert.Equal(t, 0, exporter.exported["aaa"](context.Background()))
+	}
+
+	assert.NoError(t, handler.Replace(Readiness, "aaa", func(context.Context) error {
+		return fmt.Errorf("failing")
+	}))
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, 1, exporter.exported["aaa"](context.Background()))
+}
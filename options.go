@@ -0,0 +1,202 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Option configures optional behavior of a Handler created by NewHandler or
+// NewMetricsHandler.
+type Option func(*basicHandler)
+
+// WithConcurrency limits how many checks are executed at the same time while
+// serving a single request. A limit <= 0 (the default) runs every check in
+// parallel.
+func WithConcurrency(limit int) Option {
+	return func(h *basicHandler) {
+		h.concurrency = limit
+	}
+}
+
+// WithTimeout sets an overall deadline for evaluating all the checks of a
+// single request. Checks that have not returned when the deadline passes are
+// reported as failed with a timeout error. A timeout <= 0 (the default)
+// disables the deadline.
+func WithTimeout(timeout time.Duration) Option {
+	return func(h *basicHandler) {
+		h.timeout = timeout
+	}
+}
+
+// WithLivenessAfterStartup skips the liveness checks until every startup
+// check has passed, so that a slow startup can't get the application
+// restarted. Until then, the liveness endpoint always succeeds.
+func WithLivenessAfterStartup() Option {
+	return func(h *basicHandler) {
+		h.livenessAfterStartup = true
+	}
+}
+
+// WithDefaultFormat sets the format of the full check results returned when a
+// request doesn't ask for one with the ?format= query parameter. The default
+// is FormatJSON; use FormatLegacy to keep returning the flat map of older
+// releases.
+func WithDefaultFormat(format Format) Option {
+	return func(h *basicHandler) {
+		h.defaultFormat = format
+	}
+}
+
+// WithKubernetesEndpoints additionally serves /livez, /readyz and /healthz
+// endpoints that behave like the health endpoints of Kubernetes components:
+// they respond with plain text, support ?verbose for a line per check, and
+// serve a single check at a sub-path such as /readyz/database. They use the
+// same checks as /live and /ready; /healthz runs every check.
+func WithKubernetesEndpoints() Option {
+	return func(h *basicHandler) {
+		h.kubernetesEndpoints = true
+	}
+}
+
+// WithPathPrefix serves every endpoint under prefix, such as "/health" for
+// /health/live and /health/ready. The prefix must start with a slash and must
+// not end with one.
+func WithPathPrefix(prefix string) Option {
+	return func(h *basicHandler) {
+		h.pathPrefix = prefix
+	}
+}
+
+// WithLivenessPath serves the liveness endpoint at path instead of /live.
+func WithLivenessPath(path string) Option {
+	return func(h *basicHandler) {
+		h.livePath = path
+	}
+}
+
+// WithReadinessPath serves the readiness endpoint at path instead of /ready.
+func WithReadinessPath(path string) Option {
+	return func(h *basicHandler) {
+		h.readyPath = path
+	}
+}
+
+// WithStartupPath serves the startup endpoint at path instead of /startup.
+func WithStartupPath(path string) Option {
+	return func(h *basicHandler) {
+		h.startupPath = path
+	}
+}
+
+// WithMethods sets the HTTP methods the endpoints can be requested with. The
+// default only allows GET; other methods get a 405 response.
+func WithMethods(methods ...string) Option {
+	return func(h *basicHandler) {
+		h.methods = methods
+	}
+}
+
+// WithStatusCodes sets the HTTP status codes the endpoints respond with when
+// they succeed and when they fail. The defaults are 200 and 503.
+func WithStatusCodes(success, failure int) Option {
+	return func(h *basicHandler) {
+		h.successCode = success
+		h.failureCode = failure
+	}
+}
+
+// WithVerboseParam sets the name of the query parameter that asks for the
+// full check results when it is set to 1. The default is "full".
+func WithVerboseParam(name string) Option {
+	return func(h *basicHandler) {
+		h.verboseParam = name
+	}
+}
+
+// WithAuthorizer only returns the detailed check results to requests that
+// authorizer accepts, such as BearerToken or ClientCertSubject. Other requests
+// still get the status code.
+func WithAuthorizer(authorizer Authorizer) Option {
+	return func(h *basicHandler) {
+		h.authorizer = authorizer
+	}
+}
+
+// WithRedactor rewrites the error messages of the checks with redactor before
+// they are returned.
+func WithRedactor(redactor Redactor) Option {
+	return func(h *basicHandler) {
+		h.redactor = redactor
+	}
+}
+
+// WithEventStreamIntervals sets how often the /events endpoint sends a
+// heartbeat comment, which keeps proxies from closing idle connections, and a
+// snapshot of the last known statuses. The defaults are 15 seconds and one
+// minute; an interval <= 0 disables the heartbeat or the periodic snapshot.
+func WithEventStreamIntervals(heartbeat, snapshot time.Duration) Option {
+	return func(h *basicHandler) {
+		h.heartbeatInterval = heartbeat
+		h.snapshotInterval = snapshot
+	}
+}
+
+// WithHistorySize sets how many of the most recent results of every check are
+// retained for the /history endpoint. The default is 100; a size <= 0
+// disables the history.
+func WithHistorySize(size int) Option {
+	return func(h *basicHandler) {
+		h.historySize = size
+	}
+}
+
+// WithRepanic re-panics with the original value when a check panics while
+// serving a request, instead of reporting the panic as a failure of the check.
+// This is meant for tests, where a panic should fail loudly.
+func WithRepanic() Option {
+	return func(h *basicHandler) {
+		h.repanic = true
+	}
+}
+
+// WithLogger logs failed, slow and timed out checks to logger, along with
+// every change of the status of a check or endpoint. Each check logs at most
+// one entry per minute; the number of suppressed entries is reported with the
+// next one.
+func WithLogger(logger *slog.Logger) Option {
+	return func(h *basicHandler) {
+		h.logger = logger
+	}
+}
+
+// WithSlowCheckThreshold logs checks that take at least threshold to return,
+// when a logger is configured with WithLogger. A threshold <= 0 (the default)
+// disables it.
+func WithSlowCheckThreshold(threshold time.Duration) Option {
+	return func(h *basicHandler) {
+		h.slowThreshold = threshold
+	}
+}
+
+// WithTracer traces every request to the endpoints and every check execution
+// with tracer. The context passed to context-aware checks carries the trace,
+// so checks such as HTTPGetCheckContext propagate it.
+func WithTracer(tracer Tracer) Option {
+	return func(h *basicHandler) {
+		h.tracer = tracer
+	}
+}
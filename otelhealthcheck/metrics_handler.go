@@ -0,0 +1,97 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelhealthcheck
+
+import (
+	"context"
+	"time"
+
+	"github.com/heptiolabs/healthcheck"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// NewMetricsHandler returns a healthcheck.Handler that also reports metrics
+// through the provided MeterProvider, as an alternative to
+// healthcheck.NewMetricsHandler:
+//
+//   - healthcheck.status, an observable gauge that runs every check when it is
+//     collected (0 indicates success, 1 indicates failure, 2 indicates a
+//     warning)
+//   - healthcheck.duration, a histogram of the execution time of every check
+//   - healthcheck.failures, a counter of the failed executions of every check
+//
+// The metrics have the check name and kind as attributes. Any options are
+// passed through to healthcheck.NewHandler.
+func NewMetricsHandler(provider metric.MeterProvider, opts ...healthcheck.Option) healthcheck.Handler {
+	meter := provider.Meter(instrumentationName)
+	exporter := &exporter{meter: meter}
+
+	// the SDK returns usable instruments even if it reports an error
+	handle := func(err error) {
+		if err != nil {
+			otel.Handle(err)
+		}
+	}
+	var err error
+	exporter.status, err = meter.Int64ObservableGauge("healthcheck.status",
+		metric.WithDescription("Current check status (0 indicates success, 1 indicates failure, 2 indicates a warning)"))
+	handle(err)
+	exporter.duration, err = meter.Float64Histogram("healthcheck.duration",
+		metric.WithDescription("Execution time of the check"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10))
+	handle(err)
+	exporter.failures, err = meter.Int64Counter("healthcheck.failures",
+		metric.WithDescription("Number of failed executions of the check"),
+		metric.WithUnit("{failure}"))
+	handle(err)
+	return healthcheck.NewMetricsExporterHandler(exporter, opts...)
+}
+
+// exporter records the duration and failures of every check execution, and
+// observes the status of every check by running it whenever the meter is
+// collected.
+type exporter struct {
+	meter    metric.Meter
+	status   metric.Int64ObservableGauge
+	duration metric.Float64Histogram
+	failures metric.Int64Counter
+}
+
+func (e *exporter) Instrument(kind healthcheck.Kind, name string, check healthcheck.CheckContext) healthcheck.CheckContext {
+	attributes := metric.WithAttributes(checkAttribute.String(name), kindAttribute.String(string(kind)))
+	return func(ctx context.Context) error {
+		start := time.Now()
+		err := check(ctx)
+		e.duration.Record(ctx, time.Since(start).Seconds(), attributes)
+		if err != nil {
+			e.failures.Add(ctx, 1, attributes)
+		}
+		return err
+	}
+}
+
+func (e *exporter) Export(kind healthcheck.Kind, name string, status func(context.Context) int) (func(), error) {
+	attributes := metric.WithAttributes(checkAttribute.String(name), kindAttribute.String(string(kind)))
+	registration, err := e.meter.RegisterCallback(func(ctx context.Context, observer metric.Observer) error {
+		observer.ObserveInt64(e.status, int64(status(ctx)), attributes)
+		return nil
+	}, e.status)
+	if err != nil {
+		return nil, err
+	}
+	return func() { registration.Unregister() }, nil
+}
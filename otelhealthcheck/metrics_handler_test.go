@@ -0,0 +1,142 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelhealthcheck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/heptiolabs/healthcheck"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// otelMetrics are the metrics of every check, keyed by "kind/name".
+type otelMetrics struct {
+	status    map[string]int64
+	durations map[string]uint64
+	failures  map[string]int64
+}
+
+func collectOTelMetrics(t *testing.T, reader sdkmetric.Reader) otelMetrics {
+	var data metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &data))
+	metrics := otelMetrics{
+		status:    make(map[string]int64),
+		durations: make(map[string]uint64),
+		failures:  make(map[string]int64),
+	}
+	for _, scope := range data.ScopeMetrics {
+		assert.Equal(t, instrumentationName, scope.Scope.Name)
+		for _, m := range scope.Metrics {
+			switch m.Name {
+			case "healthcheck.status":
+				for _, point := range m.Data.(metricdata.Gauge[int64]).DataPoints {
+					metrics.status[otelMetricsKey(point.Attributes)] = point.Value
+				}
+			case "healthcheck.duration":
+				assert.Equal(t, "s", m.Unit)
+				for _, point := range m.Data.(metricdata.Histogram[float64]).DataPoints {
+					metrics.durations[otelMetricsKey(point.Attributes)] = point.Count
+				}
+			case "healthcheck.failures":
+				for _, point := range m.Data.(metricdata.Sum[int64]).DataPoints {
+					metrics.failures[otelMetricsKey(point.Attributes)] = point.Value
+				}
+			}
+		}
+	}
+	return metrics
+}
+
+func otelMetricsKey(attributes attribute.Set) string {
+	name, _ := attributes.Value(checkAttribute)
+	kind, _ := attributes.Value(kindAttribute)
+	return kind.AsString() + "/" + name.AsString()
+}
+
+func TestMetricsHandler(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	handler := NewMetricsHandler(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	handler.AddLivenessCheck("aaa", func() error {
+		return nil
+	})
+	handler.AddReadinessCheck("bbb", func() error {
+		return fmt.Errorf("failing")
+	})
+	assert.NoError(t, handler.Register(healthcheck.Readiness, "ccc", func(context.Context) error {
+		return fmt.Errorf("failing")
+	}, healthcheck.WithSeverity(healthcheck.SeverityWarning)))
+
+	// probes record the duration and failures of every check
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/live", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// collecting the status gauge runs every check once more, and the
+	// readiness probe runs the liveness checks as well
+	metrics := collectOTelMetrics(t, reader)
+	assert.Equal(t, map[string]int64{"liveness/aaa": 0, "readiness/bbb": 1, "readiness/ccc": 2}, metrics.status)
+	assert.Equal(t, map[string]uint64{"liveness/aaa": 3, "readiness/bbb": 2, "readiness/ccc": 2}, metrics.durations)
+	assert.Equal(t, map[string]int64{"readiness/bbb": 2, "readiness/ccc": 2}, metrics.failures)
+
+}
+
+func TestMetricsHandlerRemoveAndReplace(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	handler := NewMetricsHandler(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	status := func() map[string]int64 {
+		return collectOTelMetrics(t, reader).status
+	}
+
+	handler.AddReadinessCheck("aaa", func() error {
+		return nil
+	})
+	assert.NoError(t, handler.Register(healthcheck.Liveness, "bbb", func(context.Context) error {
+		return nil
+	}))
+	assert.Equal(t, healthcheck.ErrDuplicateCheck, handler.Register(healthcheck.Liveness, "bbb", func(context.Context) error {
+		return nil
+	}))
+	assert.Equal(t, map[string]int64{"readiness/aaa": 0, "liveness/bbb": 0}, status())
+
+	// replacing a check swaps out its callback
+	assert.NoError(t, handler.Replace(healthcheck.Liveness, "bbb", func(context.Context) error {
+		return fmt.Errorf("failing")
+	}))
+	assert.Equal(t, map[string]int64{"readiness/aaa": 0, "liveness/bbb": 1}, status())
+
+	handler.RemoveReadinessCheck("aaa")
+	handler.RemoveLivenessCheck("bbb")
+	assert.Empty(t, status())
+
+	// startup checks stop running once they have passed
+	var runs int
+	handler.AddStartupCheck("ddd", func() error {
+		runs++
+		return nil
+	})
+	assert.Equal(t, map[string]int64{"startup/ddd": 0}, status())
+	assert.Equal(t, map[string]int64{"startup/ddd": 0}, status())
+	assert.Equal(t, 1, runs)
+}
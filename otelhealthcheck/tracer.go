@@ -0,0 +1,91 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelhealthcheck
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/heptiolabs/healthcheck"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the instrumentation name of this package.
+const instrumentationName = "github.com/heptiolabs/healthcheck/otelhealthcheck"
+
+// the attributes of the spans and metrics
+const (
+	checkAttribute  = attribute.Key("healthcheck.check")
+	kindAttribute   = attribute.Key("healthcheck.kind")
+	statusAttribute = attribute.Key("healthcheck.status")
+)
+
+// NewTracer returns a healthcheck.Tracer for use with healthcheck.WithTracer
+// that starts a span from provider for every request to the endpoints, with
+// a child span for every check execution. Trace contexts are extracted from
+// and injected into requests with the global propagator.
+func NewTracer(provider trace.TracerProvider) healthcheck.Tracer {
+	return &tracer{tracer: provider.Tracer(instrumentationName)}
+}
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// StartEndpoint starts the span of a request to the endpoint of kind. If the
+// request context doesn't carry a span yet, such as one started by a
+// middleware, the span continues any trace propagated with the request
+// headers.
+func (t *tracer) StartEndpoint(r *http.Request, kind healthcheck.Kind) (context.Context, func(healthcheck.Status)) {
+	ctx := r.Context()
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
+	}
+	ctx, span := t.tracer.Start(ctx, "healthcheck "+string(kind), trace.WithAttributes(kindAttribute.String(string(kind))))
+	return ctx, func(status healthcheck.Status) {
+		span.SetAttributes(statusAttribute.String(string(status)))
+		if status == healthcheck.StatusFailed {
+			span.SetStatus(codes.Error, "one or more checks failed")
+		}
+		span.End()
+	}
+}
+
+// StartCheck starts the span of a single check execution. Errors of checks
+// that only warn are recorded, but don't mark the span as failed.
+func (t *tracer) StartCheck(ctx context.Context, kind healthcheck.Kind, name string) (context.Context, func(healthcheck.Status, error)) {
+	ctx, span := t.tracer.Start(ctx, "check "+name, trace.WithAttributes(
+		checkAttribute.String(name),
+		kindAttribute.String(string(kind)),
+	))
+	return ctx, func(status healthcheck.Status, err error) {
+		span.SetAttributes(statusAttribute.String(string(status)))
+		if err != nil {
+			span.RecordError(err)
+			if status == healthcheck.StatusFailed {
+				span.SetStatus(codes.Error, err.Error())
+			}
+		}
+		span.End()
+	}
+}
+
+func (t *tracer) Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
@@ -0,0 +1,137 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelhealthcheck
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/heptiolabs/healthcheck"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	propagator := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagator)
+
+	// the upstream records the trace context it was called with
+	traceparent := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent <- r.Header.Get("traceparent")
+	}))
+	defer upstream.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	h := healthcheck.NewHandler(healthcheck.WithTracer(NewTracer(provider)))
+	h.AddReadinessCheckContext("upstream", healthcheck.HTTPGetCheckContext(upstream.URL, time.Second))
+	h.AddReadinessCheck("database", func() error {
+		return errors.New("connection refused")
+	})
+	h.AddReadinessCheck("cache", func() error {
+		return errors.New("cache miss rate too high")
+	}, healthcheck.WithSeverity(healthcheck.SeverityWarning))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	assert.Len(t, spans, 4)
+	endpoint := spans["healthcheck readiness"]
+	assert.Equal(t, codes.Error, endpoint.Status.Code)
+	assert.Contains(t, endpoint.Attributes, attribute.String("healthcheck.status", "failed"))
+
+	tests := []struct {
+		name   string
+		status healthcheck.Status
+		code   codes.Code
+		events int
+	}{
+		{name: "upstream", status: healthcheck.StatusOK, code: codes.Unset},
+		{name: "database", status: healthcheck.StatusFailed, code: codes.Error, events: 1},
+		{name: "cache", status: healthcheck.StatusWarning, code: codes.Unset, events: 1},
+	}
+	for _, tt := range tests {
+		span, ok := spans["check "+tt.name]
+		if !assert.True(t, ok, tt.name) {
+			continue
+		}
+		assert.Equal(t, endpoint.SpanContext.SpanID(), span.Parent.SpanID(), tt.name)
+		assert.Equal(t, endpoint.SpanContext.TraceID(), span.SpanContext.TraceID(), tt.name)
+		assert.Equal(t, []attribute.KeyValue{
+			attribute.String("healthcheck.check", tt.name),
+			attribute.String("healthcheck.kind", "readiness"),
+			attribute.String("healthcheck.status", string(tt.status)),
+		}, span.Attributes, tt.name)
+		assert.Equal(t, tt.code, span.Status.Code, tt.name)
+		assert.Len(t, span.Events, tt.events, tt.name)
+	}
+
+	// the upstream was called within the span of its check
+	check := spans["check upstream"].SpanContext
+	assert.Equal(t, "00-"+check.TraceID().String()+"-"+check.SpanID().String()+"-01", <-traceparent)
+}
+
+func TestTracerContinuesTrace(t *testing.T) {
+	propagator := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagator)
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	h := healthcheck.NewHandler(healthcheck.WithTracer(NewTracer(provider)))
+	h.AddLivenessCheck("goroutines", func() error {
+		return nil
+	})
+
+	// a trace propagated with the request headers is continued
+	req := httptest.NewRequest("GET", "/live", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 2) {
+		for _, span := range spans {
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String())
+		}
+	}
+
+	// but a span that is already active, such as one started by a middleware,
+	// wins over the headers
+	exporter.Reset()
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "middleware")
+	h.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+	parent.End()
+	spans = exporter.GetSpans()
+	if assert.Len(t, spans, 3) {
+		for _, span := range spans {
+			assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext.TraceID())
+		}
+	}
+}
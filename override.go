@@ -0,0 +1,106 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// override decides the outcome of an endpoint instead of its checks.
+type override struct {
+	// name is the name of the override in the Kubernetes-style output
+	name   string
+	ready  bool
+	reason string
+}
+
+// status returns the overall status of an endpoint whose checks resulted in
+// checks. It is safe to call on a nil override, which returns checks.
+func (o *override) status(checks Status) Status {
+	switch {
+	case o == nil:
+		return checks
+	case o.ready:
+		return StatusOK
+	default:
+		return StatusFailed
+	}
+}
+
+// overrideState is the response body of the override endpoint.
+type overrideState struct {
+	Ready  bool   `json:"ready"`
+	Reason string `json:"reason"`
+}
+
+func (s *basicHandler) OverrideReadiness(ready bool, reason string) {
+	if reason == "" {
+		reason = "forced not ready"
+		if ready {
+			reason = "forced ready"
+		}
+	}
+	s.readinessMutex.Lock()
+	defer s.readinessMutex.Unlock()
+	s.readiness = &override{name: "override", ready: ready, reason: reason}
+}
+
+func (s *basicHandler) ClearReadinessOverride() {
+	s.readinessMutex.Lock()
+	defer s.readinessMutex.Unlock()
+	s.readiness = nil
+}
+
+func (s *basicHandler) OverrideEndpoint(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		ready, err := strconv.ParseBool(r.FormValue("ready"))
+		if err != nil {
+			http.Error(w, "ready must be true or false", http.StatusBadRequest)
+			return
+		}
+		s.OverrideReadiness(ready, r.FormValue("reason"))
+	case http.MethodDelete:
+		s.ClearReadinessOverride()
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	s.readinessMutex.Lock()
+	o := s.readiness
+	s.readinessMutex.Unlock()
+	if o == nil {
+		w.Write([]byte("{}\n"))
+		return
+	}
+	json.NewEncoder(w).Encode(overrideState{Ready: o.ready, Reason: o.reason})
+}
+
+// readinessOverride returns the override of the readiness endpoints, or nil
+// if their checks decide. Draining wins over a manual override.
+func (s *basicHandler) readinessOverride() *override {
+	if atomic.LoadInt32(&s.draining) == 1 {
+		return drainOverride
+	}
+	s.readinessMutex.Lock()
+	defer s.readinessMutex.Unlock()
+	return s.readiness
+}
@@ -0,0 +1,52 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package healthcheck
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// OverrideOnSignals forces the readiness endpoints of handler to fail when
+// the process receives SIGUSR1, and clears the override on SIGUSR2, so an
+// operator can take an instance out of rotation with kill(1). Call the
+// returned function to stop listening for the signals.
+func OverrideOnSignals(handler Handler) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				if sig == syscall.SIGUSR1 {
+					handler.OverrideReadiness(false, "SIGUSR1")
+				} else {
+					handler.ClearReadinessOverride()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
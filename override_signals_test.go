@@ -0,0 +1,53 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package healthcheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverrideOnSignals(t *testing.T) {
+	h := NewHandler()
+	stop := OverrideOnSignals(h)
+	defer stop()
+
+	// signals are delivered asynchronously, so wait for the expected code
+	waitForReady := func(expect int) int {
+		var code int
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+			if code = rr.Code; code == expect {
+				break
+			}
+		}
+		return code
+	}
+
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.Equal(t, http.StatusServiceUnavailable, waitForReady(http.StatusServiceUnavailable))
+
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+	assert.Equal(t, http.StatusOK, waitForReady(http.StatusOK))
+}
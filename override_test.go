@@ -0,0 +1,140 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverrideReadiness(t *testing.T) {
+	h := NewHandler(WithKubernetesEndpoints())
+	h.AddReadinessCheck("database", func() error {
+		return errors.New("connection refused")
+	})
+	h.AddLivenessCheck("goroutines", func() error {
+		return nil
+	})
+
+	get := func(path string) (int, string) {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		return rr.Code, rr.Body.String()
+	}
+	report := func() jsonReport {
+		_, body := get("/ready?full=1")
+		var report jsonReport
+		assert.NoError(t, json.Unmarshal([]byte(body), &report))
+		return report
+	}
+
+	code, _ := get("/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Empty(t, report().Reason)
+
+	// forcing ready ignores the failing check, but keeps reporting it
+	h.OverrideReadiness(true, "database migration in progress")
+	code, _ = get("/ready")
+	assert.Equal(t, http.StatusOK, code)
+	r := report()
+	assert.Equal(t, StatusOK, r.Status)
+	assert.Equal(t, "database migration in progress", r.Reason)
+	assert.Equal(t, StatusFailed, r.Checks["database"].Status)
+	code, body := get("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body)
+
+	// forcing not ready fails even when every check passes
+	h.OverrideReadiness(false, "")
+	code, _ = get("/ready?exclude=database")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "forced not ready", report().Reason)
+	code, body = get("/readyz?exclude=database")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "[+]database excluded: ok\n[+]goroutines ok\n[-]override failed\nreadyz check failed\n", body)
+
+	// liveness is unaffected
+	code, _ = get("/live")
+	assert.Equal(t, http.StatusOK, code)
+
+	h.ClearReadinessOverride()
+	code, _ = get("/ready?exclude=database")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, report().Reason)
+
+	// draining wins over forcing ready
+	h.OverrideReadiness(true, "")
+	h.Drain(context.Background(), time.Millisecond)
+	code, _ = get("/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "draining", report().Reason)
+}
+
+func TestOverrideEndpoint(t *testing.T) {
+	h := NewHandler()
+	h.AddReadinessCheck("database", func() error {
+		return nil
+	})
+	admin := http.NewServeMux()
+	admin.HandleFunc("/override", h.OverrideEndpoint)
+
+	tests := []struct {
+		method string
+		target string
+		body   string
+		expect int
+		state  string
+		ready  int
+	}{
+		{method: "GET", target: "/override", expect: http.StatusOK, state: "{}\n", ready: http.StatusOK},
+		{method: "POST", target: "/override?ready=false&reason=debugging", expect: http.StatusOK, state: "{\"ready\":false,\"reason\":\"debugging\"}\n", ready: http.StatusServiceUnavailable},
+		{method: "GET", target: "/override", expect: http.StatusOK, state: "{\"ready\":false,\"reason\":\"debugging\"}\n", ready: http.StatusServiceUnavailable},
+		{method: "POST", target: "/override", body: "ready=true", expect: http.StatusOK, state: "{\"ready\":true,\"reason\":\"forced ready\"}\n", ready: http.StatusOK},
+		{method: "POST", target: "/override?ready=maybe", expect: http.StatusBadRequest, ready: http.StatusOK},
+		{method: "PUT", target: "/override", expect: http.StatusMethodNotAllowed, ready: http.StatusOK},
+		{method: "DELETE", target: "/override", expect: http.StatusOK, state: "{}\n", ready: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			rr := httptest.NewRecorder()
+			admin.ServeHTTP(rr, req)
+			assert.Equal(t, tt.expect, rr.Code)
+			if tt.state != "" {
+				assert.Equal(t, tt.state, rr.Body.String())
+			}
+
+			rr = httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+			assert.Equal(t, tt.ready, rr.Code)
+		})
+	}
+
+	// the override endpoint isn't served by the handler itself
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("POST", "/override?ready=false", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
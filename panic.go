@@ -0,0 +1,73 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// maxStackFrames is the number of frames kept in the stack of a panicError.
+const maxStackFrames = 10
+
+// panicError is the error a check that panicked is reported with.
+type panicError struct {
+	value interface{}
+	stack string
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", e.value, e.stack)
+}
+
+// newPanicError returns a panicError for value with the stack of the calling
+// goroutine. It must be called from the deferred function that recovered.
+func newPanicError(value interface{}) *panicError {
+	stack := string(debug.Stack())
+
+	// skip the frames of the recovery, up to and including the call to panic
+	if i := strings.Index(stack, "\npanic("); i >= 0 {
+		if lines := strings.SplitN(stack[i+1:], "\n", 3); len(lines) == 3 {
+			stack = lines[2]
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(stack), "\n")
+	if len(lines) > 2*maxStackFrames {
+		lines = append(lines[:2*maxStackFrames], "...")
+	}
+	return &panicError{value: value, stack: strings.Join(lines, "\n")}
+}
+
+// callCheck calls check, turning a panic into a panicError.
+func callCheck(ctx context.Context, check CheckContext) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = newPanicError(value)
+		}
+	}()
+	return check(ctx)
+}
+
+// callSimpleCheck calls check, turning a panic into a panicError.
+func callSimpleCheck(check Check) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = newPanicError(value)
+		}
+	}()
+	return check()
+}
@@ -0,0 +1,97 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+// nilMapCheck panics with a runtime error.
+func nilMapCheck() error {
+	var m map[string]int
+	m["boom"]++
+	return nil
+}
+
+func TestPanicRecovery(t *testing.T) {
+	h := NewHandler()
+	h.AddReadinessCheck("buggy", nilMapCheck)
+	h.AddReadinessCheck("healthy", func() error {
+		return nil
+	})
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready?full=1", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	var report jsonReport
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, StatusOK, report.Checks["healthy"].Status)
+	buggy := report.Checks["buggy"]
+	assert.Equal(t, StatusFailed, buggy.Status)
+	assert.True(t, strings.HasPrefix(buggy.Error, "panic: assignment to entry in nil map\n"), buggy.Error)
+
+	// the stack starts at the check, without the frames of the recovery
+	lines := strings.Split(buggy.Error, "\n")
+	if assert.True(t, len(lines) > 1) {
+		assert.Contains(t, lines[1], "nilMapCheck")
+	}
+	assert.NotContains(t, buggy.Error, "runtime/debug.Stack")
+	assert.True(t, len(lines) <= 2+2*maxStackFrames, "expected a trimmed stack, got %d lines", len(lines))
+}
+
+func TestRepanic(t *testing.T) {
+	h := NewHandler(WithRepanic())
+	h.AddLivenessCheck("buggy", nilMapCheck)
+	assert.Panics(t, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/live", nil))
+	})
+}
+
+func TestPanicRecoveryWrappers(t *testing.T) {
+	err := Timeout(nilMapCheck, time.Second)()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "panic: assignment to entry in nil map")
+	}
+
+	// cached checks run on their own goroutine
+	if err := Cache(nilMapCheck, time.Second)(); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "panic: assignment to entry in nil map")
+	}
+
+	async := Async(nilMapCheck, time.Hour)
+	for deadline := time.Now().Add(time.Second); async() == ErrNoData && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if err := async(); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "panic: assignment to entry in nil map")
+	}
+
+	registry := prometheus.NewRegistry()
+	handler := NewMetricsHandler(registry, "test")
+	handler.AddLivenessCheck("buggy", nilMapCheck)
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	if assert.Len(t, families, 1) {
+		assert.Equal(t, 1.0, families[0].GetMetric()[0].GetGauge().GetValue())
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Format is the shape of the response body returned when the full check
+// results are requested with ?full=1. It can be selected per request with the
+// ?format= query parameter.
+type Format string
+
+const (
+	// FormatJSON reports the overall status along with a result object for
+	// every check. This is the default.
+	FormatJSON Format = "json"
+
+	// FormatLegacy reports a flat map from check name to either "OK" or the
+	// error message, which is what older releases returned.
+	FormatLegacy Format = "legacy"
+
+	// FormatHealthJSON reports results in the application/health+json format
+	// described in https://tools.ietf.org/html/draft-inadarei-api-health-check
+	// with every check keyed by name in the "checks" object. It is also
+	// selected by an "Accept: application/health+json" request header.
+	FormatHealthJSON Format = "health"
+)
+
+// jsonReport is the FormatJSON response body.
+type jsonReport struct {
+	Status Status                `json:"status"`
+	Reason string                `json:"reason,omitempty"`
+	Counts map[Status]int        `json:"counts"`
+	Checks map[string]jsonResult `json:"checks"`
+}
+
+// jsonResult is the FormatJSON result of a single check. Checks that were
+// not executed have no duration or timestamp, the severity is only listed for
+// non-critical checks, and the streaks only for checks with thresholds.
+type jsonResult struct {
+	Status    Status     `json:"status"`
+	Kind      Kind       `json:"kind"`
+	Tags      []string   `json:"tags,omitempty"`
+	Severity  Severity   `json:"severity,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Duration  string     `json:"duration,omitempty"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+
+	ConsecutiveFailures  int `json:"consecutiveFailures,omitempty"`
+	ConsecutiveSuccesses int `json:"consecutiveSuccesses,omitempty"`
+}
+
+// overallStatus returns StatusFailed if any of the results failed, or
+// StatusDegraded if any of them only warned.
+func overallStatus(results []checkResult) Status {
+	status := StatusOK
+	for _, result := range results {
+		switch result.status {
+		case StatusFailed:
+			return StatusFailed
+		case StatusWarning:
+			status = StatusDegraded
+		}
+	}
+	return status
+}
+
+// format returns the response format requested by r, either with the
+// ?format= query parameter or the Accept header.
+func (s *basicHandler) format(r *http.Request) Format {
+	switch format := Format(r.URL.Query().Get("format")); format {
+	case FormatJSON, FormatLegacy, FormatHealthJSON:
+		return format
+	}
+	if acceptsHealthJSON(r) {
+		return FormatHealthJSON
+	}
+	return s.defaultFormat
+}
+
+func (s *basicHandler) writeResults(w http.ResponseWriter, r *http.Request, results []checkResult, override *override) {
+	status := s.statusCode(override.status(overallStatus(results)))
+	full := r.URL.Query().Get(s.verboseParam) == "1"
+	format := s.format(r)
+
+	// callers that aren't authorized only get the status
+	if !s.authorized(r) {
+		full = false
+		if override != nil {
+			o := *override
+			o.reason = ""
+			override = &o
+		}
+	}
+	results = s.redact(results)
+
+	// write out the response code and content type header
+	if format == FormatHealthJSON {
+		w.Header().Set("Content-Type", healthJSONContentType)
+	} else {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+	w.WriteHeader(status)
+
+	// unless ?full=1, return an empty body. Kubernetes only cares about the
+	// HTTP status code, so we won't waste bytes on the full body. The
+	// application/health+json format always includes the status, though.
+	if !full && format != FormatHealthJSON {
+		w.Write([]byte("{}\n"))
+		return
+	}
+
+	// otherwise, write the JSON body ignoring any encoding errors (which
+	// shouldn't really be possible since we're encoding plain structs).
+	var body interface{}
+	switch format {
+	case FormatLegacy:
+		body = legacyReport(results)
+	case FormatHealthJSON:
+		body = newHealthReport(results, full, override)
+	default:
+		body = newJSONReport(results, override)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.Encode(body)
+}
+
+// legacyReport maps each check name to "OK", "excluded" or its error message.
+// If several checks share a name, a failure wins.
+func legacyReport(results []checkResult) map[string]string {
+	report := make(map[string]string, len(results))
+	for _, result := range results {
+		if result.err != nil {
+			report[result.name] = result.err.Error()
+		} else if _, exists := report[result.name]; !exists {
+			if result.status == StatusExcluded {
+				report[result.name] = string(StatusExcluded)
+			} else {
+				report[result.name] = "OK"
+			}
+		}
+	}
+	return report
+}
+
+// newJSONReport maps the results into the FormatJSON format. If override is
+// set, it decides the overall status and provides the reason.
+func newJSONReport(results []checkResult, override *override) jsonReport {
+	report := jsonReport{
+		Status: override.status(overallStatus(results)),
+		Counts: make(map[Status]int),
+		Checks: make(map[string]jsonResult, len(results)),
+	}
+	if override != nil {
+		report.Reason = override.reason
+	}
+	for _, result := range results {
+		report.Counts[result.status]++
+
+		// if several checks share a name, a failure wins
+		if existing, exists := report.Checks[result.name]; exists && existing.Status == StatusFailed {
+			continue
+		}
+		jr := jsonResult{
+			Status:               result.status,
+			Kind:                 result.kind,
+			Tags:                 result.tags,
+			ConsecutiveFailures:  result.consecutiveFailures,
+			ConsecutiveSuccesses: result.consecutiveSuccesses,
+		}
+		if result.severity != SeverityCritical {
+			jr.Severity = result.severity
+		}
+		if result.err != nil {
+			jr.Error = result.err.Error()
+		}
+		// excluded and skipped checks didn't run
+		if !result.start.IsZero() && result.status != StatusSkipped {
+			timestamp := result.start.UTC()
+			jr.Timestamp = &timestamp
+			jr.Duration = result.duration.String()
+		}
+		report.Checks[result.name] = jr
+	}
+	return report
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONFormat(t *testing.T) {
+	h := NewHandler()
+	h.AddLivenessCheck("live", func() error {
+		return nil
+	})
+	h.AddReadinessCheck("ready", func() error {
+		time.Sleep(5 * time.Millisecond)
+		return errors.New("upstream unavailable")
+	})
+
+	before := time.Now()
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready?full=1", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "application/json; charset=utf-8", rr.Header().Get("Content-Type"))
+
+	var report jsonReport
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, map[Status]int{StatusOK: 1, StatusFailed: 1}, report.Counts)
+
+	live := report.Checks["live"]
+	assert.Equal(t, StatusOK, live.Status)
+	assert.Equal(t, Liveness, live.Kind)
+	assert.Empty(t, live.Error)
+
+	ready := report.Checks["ready"]
+	assert.Equal(t, StatusFailed, ready.Status)
+	assert.Equal(t, Readiness, ready.Kind)
+	assert.Equal(t, "upstream unavailable", ready.Error)
+	if assert.NotNil(t, ready.Timestamp) {
+		assert.WithinDuration(t, before, *ready.Timestamp, time.Second)
+	}
+	duration, err := time.ParseDuration(ready.Duration)
+	assert.NoError(t, err)
+	assert.True(t, duration >= 5*time.Millisecond, "expected the duration of the check, got %s", duration)
+}
+
+func TestLegacyFormat(t *testing.T) {
+	h := NewHandler(WithDefaultFormat(FormatLegacy))
+	h.AddReadinessCheck("ready", func() error {
+		return errors.New("upstream unavailable")
+	})
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready?full=1", nil))
+	assert.Equal(t, "{\n    \"ready\": \"upstream unavailable\"\n}\n", rr.Body.String())
+
+	// the query parameter overrides the default
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready?full=1&format=json", nil))
+	var report jsonReport
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, StatusFailed, report.Status)
+}
@@ -0,0 +1,54 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// startupCheck wraps a startup check so that it is latched once it passes:
+// after its first success it is never executed again and always passes.
+type startupCheck struct {
+	check  CheckContext
+	passed int32
+}
+
+func (c *startupCheck) run(ctx context.Context) error {
+	if c.hasPassed() {
+		return nil
+	}
+	err := c.check(ctx)
+	if err == nil {
+		atomic.StoreInt32(&c.passed, 1)
+	}
+	return err
+}
+
+func (c *startupCheck) hasPassed() bool {
+	return atomic.LoadInt32(&c.passed) == 1
+}
+
+// started returns whether every startup check has passed.
+func (s *basicHandler) started() bool {
+	s.checksMutex.RLock()
+	defer s.checksMutex.RUnlock()
+	for _, check := range s.checks[Startup] {
+		if !check.latch.hasPassed() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"sync"
+)
+
+// thresholds tracks the consecutive failures and successes of a check
+// registered with WithThresholds, and whether it is reported as failing.
+// Like the kubelet, which tracks every probe separately, it keeps a separate
+// streak for each kind of endpoint that runs the check.
+type thresholds struct {
+	failureThreshold int
+	successThreshold int
+
+	mutex   sync.Mutex
+	streaks map[Kind]*streak
+}
+
+// streak is the state of the thresholds of a check on one kind of endpoint.
+type streak struct {
+	failures  int
+	successes int
+	failing   bool
+	lastErr   error
+}
+
+// apply rewrites the result of an execution of the check on an endpoint of
+// the provided kind to the status that should be reported, after updating the
+// streak of that endpoint with it if advance is set. Failures below the
+// failure threshold are reported as ok, but keep their error; successes below
+// the success threshold keep reporting the last failure.
+func (t *thresholds) apply(result *checkResult, endpoint Kind, advance bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	s, ok := t.streaks[endpoint]
+	if !ok {
+		s = &streak{}
+		t.streaks[endpoint] = s
+	}
+	if advance {
+		if result.err != nil {
+			s.failures++
+			s.successes = 0
+			s.lastErr = result.err
+		} else {
+			s.successes++
+			s.failures = 0
+		}
+		switch {
+		case !s.failing && s.failures >= t.failureThreshold:
+			s.failing = true
+		case s.failing && s.successes >= t.successThreshold:
+			s.failing = false
+		}
+	}
+
+	result.consecutiveFailures = s.failures
+	result.consecutiveSuccesses = s.successes
+	switch {
+	case s.failing && result.err == nil:
+		result.setError(s.lastErr)
+	case !s.failing && result.err != nil:
+		result.status = StatusOK
+	}
+}
+
+// applyThresholds applies the thresholds of the check to its result on an
+// endpoint of the provided kind, if it has any. The streak only advances if
+// advance is set, so requests for the details don't count as probes.
+func (c *registeredCheck) applyThresholds(result *checkResult, endpoint Kind, advance bool) {
+	if c.thresholds != nil {
+		c.thresholds.apply(result, endpoint, advance)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThresholds(t *testing.T) {
+	h := NewHandler()
+	var err error
+	h.AddReadinessCheck("upstream", func() error {
+		return err
+	}, WithThresholds(3, 2))
+
+	refused := errors.New("connection refused")
+	tests := []struct {
+		err       error
+		expect    int
+		status    Status
+		error     string
+		failures  int
+		successes int
+	}{
+		{err: nil, expect: http.StatusOK, status: StatusOK, successes: 1},
+		// failures below the threshold are tolerated, but shown
+		{err: refused, expect: http.StatusOK, status: StatusOK, error: "connection refused", failures: 1},
+		{err: refused, expect: http.StatusOK, status: StatusOK, error: "connection refused", failures: 2},
+		{err: nil, expect: http.StatusOK, status: StatusOK, successes: 1},
+		{err: refused, expect: http.StatusOK, status: StatusOK, error: "connection refused", failures: 1},
+		{err: refused, expect: http.StatusOK, status: StatusOK, error: "connection refused", failures: 2},
+		{err: refused, expect: http.StatusServiceUnavailable, status: StatusFailed, error: "connection refused", failures: 3},
+		// it takes two successes to recover, until then the last error is reported
+		{err: nil, expect: http.StatusServiceUnavailable, status: StatusFailed, error: "connection refused", successes: 1},
+		{err: nil, expect: http.StatusOK, status: StatusOK, successes: 2},
+	}
+	for i, tt := range tests {
+		err = tt.err
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+		assert.Equal(t, tt.expect, rr.Code, "probe %d", i)
+
+		// asking for the details shows the streak without advancing it
+		rr = httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready?full=1", nil))
+		assert.Equal(t, tt.expect, rr.Code, "probe %d", i)
+		var report jsonReport
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+		result := report.Checks["upstream"]
+		assert.Equal(t, tt.status, result.Status, "probe %d", i)
+		assert.Equal(t, tt.error, result.Error, "probe %d", i)
+		assert.Equal(t, tt.failures, result.ConsecutiveFailures, "probe %d", i)
+		assert.Equal(t, tt.successes, result.ConsecutiveSuccesses, "probe %d", i)
+	}
+}
+
+func TestThresholdsPerEndpoint(t *testing.T) {
+	h := NewHandler()
+	h.AddLivenessCheck("deadlock", func() error {
+		return errors.New("worker stuck")
+	}, WithThresholds(2, 1))
+
+	get := func(path string) int {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		return rr.Code
+	}
+
+	// /ready counts the liveness check on its own, without any /live probes
+	assert.Equal(t, http.StatusOK, get("/ready"))
+	assert.Equal(t, http.StatusOK, get("/ready?full=1"))
+	assert.Equal(t, http.StatusServiceUnavailable, get("/ready"))
+
+	// while /live keeps its own streak
+	assert.Equal(t, http.StatusOK, get("/live"))
+	assert.Equal(t, http.StatusServiceUnavailable, get("/live"))
+}
+
+func TestThresholdsSingleCheck(t *testing.T) {
+	h := NewHandler(WithKubernetesEndpoints())
+	h.AddReadinessCheck("database", func() error {
+		return errors.New("connection refused")
+	}, WithThresholds(2, 1))
+
+	get := func(path string) int {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		return rr.Code
+	}
+
+	// probes of a single check count, and share the streak of their endpoint
+	assert.Equal(t, http.StatusOK, get("/ready/database"))
+	assert.Equal(t, http.StatusServiceUnavailable, get("/ready/database"))
+	assert.Equal(t, http.StatusServiceUnavailable, get("/readyz/database"))
+	assert.Equal(t, http.StatusServiceUnavailable, get("/ready"))
+}
+
+func TestThresholdsMinimum(t *testing.T) {
+	h := NewHandler()
+	h.AddReadinessCheck("upstream", func() error {
+		return errors.New("connection refused")
+	}, WithThresholds(0, -1))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
@@ -38,15 +38,31 @@ func (e timeoutError) Temporary() bool {
 
 // Timeout adds a timeout to a Check. If the underlying check takes longer than
 // the timeout, it returns an error.
-func Timeout(check Check, timeout time.Duration) Check {
+func Timeout(check Check, timeout time.Duration, opts ...WrapperOption) Check {
+	var o wrapperOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
 	return func() error {
-		c := make(chan error, 1)
-		go func() { c <- check() }()
-		select {
-		case err := <-c:
-			return err
-		case <-time.After(timeout):
-			return timeoutError(timeout)
+		start := time.Now()
+		err := runWithTimeout(check, timeout)
+		if o.notify != nil {
+			o.notify(err)
+		}
+		if o.log != nil {
+			o.log(err, time.Since(start))
 		}
+		return err
+	}
+}
+
+func runWithTimeout(check Check, timeout time.Duration) error {
+	c := make(chan error, 1)
+	go func() { c <- callSimpleCheck(check) }()
+	select {
+	case err := <-c:
+		return err
+	case <-time.After(timeout):
+		return timeoutError(timeout)
 	}
 }
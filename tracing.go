@@ -0,0 +1,84 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+)
+
+// Tracer traces the requests to the endpoints and the check executions they
+// cause, for example with the OpenTelemetry implementation in the
+// otelhealthcheck subpackage.
+type Tracer interface {
+	// StartEndpoint starts tracing a request to the endpoint of the provided
+	// kind. The returned context is passed on to the checks, and end is
+	// called with the overall status of the checks once they have returned.
+	StartEndpoint(r *http.Request, kind Kind) (ctx context.Context, end func(status Status))
+
+	// StartCheck starts tracing an execution of the check with the provided
+	// kind and name. The returned context is passed to the check, and end is
+	// called with the status and error the check returned, before any
+	// thresholds are applied.
+	StartCheck(ctx context.Context, kind Kind, name string) (checkCtx context.Context, end func(status Status, err error))
+
+	// Inject adds the trace context of ctx to the headers of a request a
+	// check makes, such as the one of HTTPGetCheckContext.
+	Inject(ctx context.Context, header http.Header)
+}
+
+// tracerKey is the context key of the Tracer of the request being served.
+type tracerKey struct{}
+
+// noopTracer is the Tracer of handlers that don't trace.
+type noopTracer struct{}
+
+func (noopTracer) StartEndpoint(r *http.Request, kind Kind) (context.Context, func(Status)) {
+	return r.Context(), func(Status) {}
+}
+
+func (noopTracer) StartCheck(ctx context.Context, kind Kind, name string) (context.Context, func(Status, error)) {
+	return ctx, func(Status, error) {}
+}
+
+func (noopTracer) Inject(context.Context, http.Header) {}
+
+// startEndpoint starts tracing a request to the endpoint of kind, and makes
+// the tracer available to the checks through the returned context.
+func (s *basicHandler) startEndpoint(r *http.Request, kind Kind) (context.Context, func([]checkResult)) {
+	ctx, end := s.tracer.StartEndpoint(r, kind)
+	return context.WithValue(ctx, tracerKey{}, s.tracer), func(results []checkResult) {
+		end(overallStatus(results))
+	}
+}
+
+// startCheck starts tracing an execution of check. The returned function
+// records the error the check returned.
+func (s *basicHandler) startCheck(ctx context.Context, check *registeredCheck) (context.Context, func(error)) {
+	ctx, end := s.tracer.StartCheck(ctx, check.kind, check.name)
+	return ctx, func(err error) {
+		result := checkResult{severity: check.severity}
+		result.setError(err)
+		end(result.status, err)
+	}
+}
+
+// injectTrace adds the trace context of ctx to header, if ctx comes from a
+// request to a handler with a Tracer.
+func injectTrace(ctx context.Context, header http.Header) {
+	if tracer, ok := ctx.Value(tracerKey{}).(Tracer); ok {
+		tracer.Inject(ctx, header)
+	}
+}
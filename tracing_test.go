@@ -0,0 +1,114 @@
+// Copyright 2017 by the contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingTracer is a Tracer that records its spans by name.
+type recordingTracer struct {
+	mutex sync.Mutex
+	spans map[string]*recordedSpan
+}
+
+type recordedSpan struct {
+	parent string
+	status Status
+	err    error
+	ended  bool
+}
+
+// spanKey is the context key of the name of the current recordedSpan.
+type spanKey struct{}
+
+func (t *recordingTracer) start(ctx context.Context, name string) (context.Context, *recordedSpan) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	parent, _ := ctx.Value(spanKey{}).(string)
+	span := &recordedSpan{parent: parent}
+	t.spans[name] = span
+	return context.WithValue(ctx, spanKey{}, name), span
+}
+
+func (t *recordingTracer) end(span *recordedSpan, status Status, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	span.status, span.err, span.ended = status, err, true
+}
+
+func (t *recordingTracer) StartEndpoint(r *http.Request, kind Kind) (context.Context, func(Status)) {
+	ctx, span := t.start(r.Context(), string(kind))
+	return ctx, func(status Status) {
+		t.end(span, status, nil)
+	}
+}
+
+func (t *recordingTracer) StartCheck(ctx context.Context, kind Kind, name string) (context.Context, func(Status, error)) {
+	ctx, span := t.start(ctx, name)
+	return ctx, func(status Status, err error) {
+		t.end(span, status, err)
+	}
+}
+
+func (t *recordingTracer) Inject(ctx context.Context, header http.Header) {
+	name, _ := ctx.Value(spanKey{}).(string)
+	header.Set("X-Span", name)
+}
+
+func TestTracer(t *testing.T) {
+	// the upstream records the span it was called within
+	spans := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		spans <- r.Header.Get("X-Span")
+	}))
+	defer upstream.Close()
+
+	tracer := &recordingTracer{spans: make(map[string]*recordedSpan)}
+	h := NewHandler(WithTracer(tracer))
+	h.AddReadinessCheckContext("upstream", HTTPGetCheckContext(upstream.URL, time.Second))
+	database := errors.New("connection refused")
+	h.AddReadinessCheck("database", func() error {
+		return database
+	})
+	cache := errors.New("cache miss rate too high")
+	h.AddReadinessCheck("cache", func() error {
+		return cache
+	}, WithSeverity(SeverityWarning))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "upstream", <-spans)
+
+	assert.Equal(t, map[string]*recordedSpan{
+		"readiness": {status: StatusFailed, ended: true},
+		"upstream":  {parent: "readiness", status: StatusOK, ended: true},
+		"database":  {parent: "readiness", status: StatusFailed, err: database, ended: true},
+		"cache":     {parent: "readiness", status: StatusWarning, err: cache, ended: true},
+	}, tracer.spans)
+
+	// checks called outside of a traced request don't inject anything
+	assert.NoError(t, HTTPGetCheck(upstream.URL, time.Second)())
+	assert.Equal(t, "", <-spans)
+}
@@ -15,32 +15,176 @@
 package healthcheck
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"time"
 )
 
+// ErrDuplicateCheck is returned by Handler.Register if a check of the same
+// kind and name is already registered.
+var ErrDuplicateCheck = errors.New("check already registered")
+
+// ErrDependencyCycle is returned by Handler.Register and Handler.Replace if a
+// check depends on itself, directly or through other checks. The Add methods
+// panic instead.
+var ErrDependencyCycle = errors.New("check dependency cycle")
+
+// ErrCheckNotFound is returned by Handler.Replace if no check of the same kind
+// and name is registered.
+var ErrCheckNotFound = errors.New("check not found")
+
 // Check is a health/readiness check.
 type Check func() error
 
+// CheckContext is a health/readiness check that receives the context of the
+// request being served. The context is canceled when the client goes away or
+// the Handler's timeout expires, so the check should return promptly once it
+// is done.
+type CheckContext func(ctx context.Context) error
+
+// Kind is the kind of a check, which determines the endpoints it affects.
+type Kind string
+
+const (
+	// Liveness checks indicate whether the application should be restarted.
+	Liveness Kind = "liveness"
+
+	// Readiness checks indicate whether the application can serve requests.
+	Readiness Kind = "readiness"
+
+	// Startup checks indicate whether the application has finished starting.
+	Startup Kind = "startup"
+)
+
+// Status is the outcome of a check, or of an endpoint as a whole.
+type Status string
+
+const (
+	// StatusOK indicates that a check (or every check) passed.
+	StatusOK Status = "ok"
+
+	// StatusFailed indicates that a check (or at least one check) failed.
+	StatusFailed Status = "failed"
+
+	// StatusWarning indicates that a check with SeverityWarning failed.
+	StatusWarning Status = "warn"
+
+	// StatusDegraded indicates that no critical check failed, but at least
+	// one check with SeverityWarning did. Endpoints still succeed when they
+	// are degraded.
+	StatusDegraded Status = "degraded"
+
+	// StatusSkipped indicates that a check wasn't executed because a check it
+	// depends on failed.
+	StatusSkipped Status = "skipped"
+
+	// StatusExcluded indicates that a check was not executed because the
+	// request excluded it with the ?exclude= query parameter.
+	StatusExcluded Status = "excluded"
+)
+
+// Severity determines whether a failing check fails its endpoints.
+type Severity string
+
+const (
+	// SeverityCritical checks fail their endpoints when they fail. This is
+	// the default.
+	SeverityCritical Severity = "critical"
+
+	// SeverityWarning checks are reported with StatusWarning when they fail,
+	// which degrades their endpoints but doesn't fail them. Use them for
+	// things that matter to humans but shouldn't take the application out
+	// of rotation.
+	SeverityWarning Severity = "warning"
+)
+
 // Handler is an http.Handler with additional methods that register health and
-// readiness checks. It handles handle "/live" and "/ready" HTTP
+// readiness checks. It handles handle "/live", "/ready" and "/startup" HTTP
 // endpoints.
 type Handler interface {
 	// The Handler is an http.Handler, so it can be exposed directly and handle
-	// /live and /ready endpoints.
+	// /live, /ready and /startup endpoints.
 	http.Handler
 
 	// AddLivenessCheck adds a check that indicates that this instance of the
 	// application should be destroyed or restarted. A failed liveness check
 	// indicates that this instance is unhealthy, not some upstream dependency.
-	// Every liveness check is also included as a readiness check.
-	AddLivenessCheck(name string, check Check)
+	// Every liveness check is also included as a readiness check. Options
+	// such as WithTags can be passed to any of the methods that add a check.
+	// The Add methods panic if DependsOn would create a dependency cycle; use
+	// Register or Replace to get ErrDependencyCycle instead.
+	AddLivenessCheck(name string, check Check, opts ...CheckOption)
 
 	// AddReadinessCheck adds a check that indicates that this instance of the
 	// application is currently unable to serve requests because of an upstream
 	// or some transient failure. If a readiness check fails, this instance
 	// should no longer receiver requests, but should not be restarted or
-	// destroyed.
-	AddReadinessCheck(name string, check Check)
+	// destroyed. It panics if DependsOn would create a dependency cycle.
+	AddReadinessCheck(name string, check Check, opts ...CheckOption)
+
+	// AddLivenessCheckContext is like AddLivenessCheck, but for a check that
+	// receives the context of the request being served.
+	AddLivenessCheckContext(name string, check CheckContext, opts ...CheckOption)
+
+	// AddReadinessCheckContext is like AddReadinessCheck, but for a check that
+	// receives the context of the request being served.
+	AddReadinessCheckContext(name string, check CheckContext, opts ...CheckOption)
+
+	// AddStartupCheck adds a check that indicates that this instance of the
+	// application has finished starting up (for example warming a cache or
+	// running migrations). Once a startup check has passed it is never
+	// executed again. Every startup check is also included as a readiness
+	// check. It panics if DependsOn would create a dependency cycle.
+	AddStartupCheck(name string, check Check, opts ...CheckOption)
+
+	// AddStartupCheckContext is like AddStartupCheck, but for a check that
+	// receives the context of the request being served.
+	AddStartupCheckContext(name string, check CheckContext, opts ...CheckOption)
+
+	// Register adds a check of the provided kind like the Add methods, but
+	// returns ErrDuplicateCheck instead of overwriting an existing check with
+	// the same kind and name, and ErrDependencyCycle instead of panicking.
+	Register(kind Kind, name string, check CheckContext, opts ...CheckOption) error
+
+	// Replace swaps out an existing check of the provided kind and name. It
+	// returns ErrCheckNotFound if there is no such check, and
+	// ErrDependencyCycle if DependsOn would create a dependency cycle.
+	Replace(kind Kind, name string, check CheckContext, opts ...CheckOption) error
+
+	// RemoveLivenessCheck removes the liveness check with the provided name,
+	// if there is one.
+	RemoveLivenessCheck(name string)
+
+	// RemoveReadinessCheck removes the readiness check with the provided name,
+	// if there is one.
+	RemoveReadinessCheck(name string)
+
+	// RemoveStartupCheck removes the startup check with the provided name, if
+	// there is one.
+	RemoveStartupCheck(name string)
+
+	// Drain makes the readiness endpoints fail with a "draining" reason,
+	// while the checks keep running and liveness is unaffected, so traffic
+	// is routed away before the application shuts down. The returned channel
+	// is closed once the grace period has passed, or earlier if ctx is done.
+	// A drain can't be undone.
+	Drain(ctx context.Context, grace time.Duration) <-chan struct{}
+
+	// OverrideReadiness forces the readiness endpoints to succeed or fail
+	// regardless of their checks, for example to take an instance out of
+	// rotation for debugging. The checks keep running, and the reason is
+	// shown in the full output. Drain wins over a forced ready.
+	OverrideReadiness(ready bool, reason string)
+
+	// ClearReadinessOverride lets the checks decide readiness again.
+	ClearReadinessOverride()
+
+	// OnStatusChange subscribes listener to changes of the status of every
+	// check, and of the aggregate status of the endpoints. Events are
+	// delivered in order from a separate goroutine, so listeners don't block
+	// probes. Call the returned function to unsubscribe.
+	OnStatusChange(listener func(Event)) (cancel func())
 
 	// LiveEndpoint is the HTTP handler for just the /live endpoint, which is
 	// useful if you need to attach it into your own HTTP handler tree.
@@ -49,4 +193,27 @@ type Handler interface {
 	// ReadyEndpoint is the HTTP handler for just the /ready endpoint, which is
 	// useful if you need to attach it into your own HTTP handler tree.
 	ReadyEndpoint(http.ResponseWriter, *http.Request)
+
+	// StartupEndpoint is the HTTP handler for just the /startup endpoint, which
+	// is useful if you need to attach it into your own HTTP handler tree.
+	StartupEndpoint(http.ResponseWriter, *http.Request)
+
+	// EventsEndpoint is the HTTP handler for just the /events endpoint, which
+	// streams status changes as Server-Sent Events along with periodic
+	// snapshots of the last known statuses. The ?check= and ?kind= query
+	// parameters select the checks to stream.
+	EventsEndpoint(http.ResponseWriter, *http.Request)
+
+	// HistoryEndpoint is the HTTP handler for just the /history endpoint,
+	// which returns the most recent results of every check along with the
+	// percentage of them that didn't fail. The ?check= and ?kind= query
+	// parameters select the checks to return.
+	HistoryEndpoint(http.ResponseWriter, *http.Request)
+
+	// OverrideEndpoint is an HTTP handler to manage the readiness override.
+	// POST with the ready (true or false) and optional reason form values
+	// sets it, DELETE clears it, and every method responds with the current
+	// override. It is not served by the Handler itself, so it can be
+	// mounted on an admin listener.
+	OverrideEndpoint(http.ResponseWriter, *http.Request)
 }